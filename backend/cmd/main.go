@@ -3,11 +3,18 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"corp-portal/internal/database"
 	"corp-portal/internal/handlers"
+	"corp-portal/internal/mailer"
 	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/oidc"
+	"corp-portal/internal/ratelimit"
+	"corp-portal/internal/scheduler"
+	"corp-portal/internal/webhooks"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,35 +27,88 @@ func main() {
 	}
 
 	database.Connect()
-
-	r := gin.Default()
+	mailer.Setup()
+	oidc.Setup()
+	scheduler.StartRecurringTasks(time.Minute)
+	scheduler.StartOverdueDigests(time.Hour)
+	scheduler.StartAuditRetention(24 * time.Hour)
+	webhooks.Setup()
+	webhooks.StartDeliveries(5 * time.Second)
+
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
+
+	// X-Forwarded-For is only honoured from TRUSTED_PROXIES, otherwise
+	// clients could pick their own IP and dodge the rate limits.
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		trustedProxies = strings.Split(value, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:8080"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Authorization", "Accept"},
 		AllowCredentials: true,
-		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "X-Next-Cursor", "Retry-After"},
 		MaxAge:           12 * time.Hour,
 	}))
 
 	r.Static("/uploads", "./uploads")
 
+	loginLimit := ratelimit.Middleware(ratelimit.Rule{Name: "login-ip", Limit: 30, Window: time.Minute}, ratelimit.ByIP)
+	registerLimit := ratelimit.Middleware(ratelimit.Rule{Name: "register-ip", Limit: 10, Window: time.Hour}, ratelimit.ByIP)
+	emailLimit := ratelimit.Middleware(ratelimit.Rule{Name: "email-ip", Limit: 5, Window: 15 * time.Minute}, ratelimit.ByIP)
+	tokenLimit := ratelimit.Middleware(ratelimit.Rule{Name: "token-ip", Limit: 30, Window: time.Minute}, ratelimit.ByIP)
+	joinLimit := ratelimit.Middleware(ratelimit.Rule{Name: "join-user", Limit: 10, Window: 15 * time.Minute}, ratelimit.ByUser)
+
 	api := r.Group("/api")
 	{
-		api.POST("/register", handlers.Register)
-		api.POST("/login", handlers.Login)
-		api.POST("/auth/google", handlers.GoogleLogin)
+		api.POST("/register", registerLimit, handlers.Register)
+		api.POST("/login", loginLimit, handlers.Login)
+		api.POST("/login/2fa", loginLimit, handlers.LoginTwoFactor)
+		api.POST("/auth/google", loginLimit, handlers.GoogleLogin)
+		api.GET("/auth/providers", handlers.GetAuthProviders)
+		api.POST("/auth/oidc/:provider", loginLimit, handlers.OIDCLogin)
+		api.POST("/token/refresh", tokenLimit, handlers.RefreshToken)
+		api.POST("/password/forgot", emailLimit, handlers.ForgotPassword)
+		api.POST("/password/reset", tokenLimit, handlers.ResetPassword)
+		api.POST("/email/verify", tokenLimit, handlers.VerifyEmail)
+		api.GET("/events", middleware.AllowQueryToken(), middleware.AuthMiddleware(), handlers.StreamEvents)
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware())
 		{
 			protected.GET("/me", handlers.GetProfile)
+			protected.GET("/notifications", handlers.GetNotifications)
+			protected.GET("/notifications/unread-count", handlers.GetUnreadNotificationCount)
+			protected.POST("/notifications/read-all", handlers.MarkAllNotificationsRead)
+			protected.POST("/notifications/:id/read", handlers.MarkNotificationRead)
+			protected.GET("/notifications/preferences", handlers.GetNotificationPreferences)
+			protected.PUT("/notifications/preferences", handlers.UpdateNotificationPreferences)
+			protected.PUT("/password", handlers.ChangePassword)
+			protected.POST("/email/verify/resend", emailLimit, handlers.ResendVerificationEmail)
+			protected.GET("/tokens", handlers.GetMyAPITokens)
+			protected.GET("/tokens/scopes", handlers.GetAPIScopes)
+			protected.POST("/tokens", handlers.CreateAPIToken)
+			protected.DELETE("/tokens/:id", handlers.RevokeAPIToken)
+			protected.GET("/2fa", handlers.GetTwoFactorStatus)
+			protected.POST("/2fa/enroll", handlers.EnrollTwoFactor)
+			protected.POST("/2fa/verify", handlers.VerifyTwoFactor)
+			protected.POST("/2fa/disable", handlers.DisableTwoFactor)
+			protected.POST("/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+			protected.POST("/logout", handlers.Logout)
+			protected.POST("/logout/all", handlers.LogoutAll)
 
 			protected.DELETE("/profile", handlers.DeleteAccount)
+			protected.GET("/profile/identities", handlers.GetMyIdentities)
+			protected.DELETE("/profile/identities/:id", handlers.UnlinkIdentity)
 			protected.POST("/profile/leave", handlers.LeaveOrganization)
 			protected.POST("/profile/upload-avatar", handlers.UploadAvatar)
 			protected.DELETE("/profile/remove-avatar", handlers.RemoveUserAvatar)
-			protected.POST("/users/:id/kick", middleware.AuthMiddleware(), handlers.KickFromOrganization)
+			protected.POST("/users/:id/kick", middleware.RequirePermission(models.PermMemberKick), handlers.KickFromOrganization)
 
 			protected.GET("/users/:id", handlers.GetUserByID)
 			protected.PUT("/users/:id", handlers.UpdateUserByID)
@@ -57,30 +117,48 @@ func main() {
 
 			protected.POST("/organizations", handlers.CreateOrganization)
 			protected.GET("/organizations/my", handlers.GetMyOrganization)
+			protected.GET("/memberships", handlers.GetMyMemberships)
+			protected.POST("/organizations/:id/switch", handlers.SwitchOrganization)
 			protected.GET("/organizations/:id", handlers.GetOrganizationByID)
-			protected.PUT("/organizations/:id", handlers.UpdateOrganization)
+			protected.PUT("/organizations/:id", middleware.RequirePermission(models.PermOrgUpdate), handlers.UpdateOrganization)
 			protected.POST("/organizations/:id/upload-avatar", handlers.UploadOrganizationAvatar)
 			protected.DELETE("/organizations/:id/avatar", handlers.RemoveOrganizationAvatar)
+			protected.POST("/organizations/:id/transfer-ownership", handlers.TransferOwnership)
+			protected.DELETE("/organizations/:id", handlers.DeleteOrganization)
 
-			protected.POST("/teams", handlers.CreateTeam)
+			protected.POST("/teams", middleware.RequirePermission(models.PermTeamCreate), handlers.CreateTeam)
 			protected.GET("/teams/:id", handlers.GetTeamByID)
 			protected.PUT("/teams/:id", handlers.UpdateTeam)
 			protected.POST("/teams/:id/upload-avatar", handlers.UploadTeamAvatar)
 			protected.DELETE("/teams/:id/avatar", handlers.RemoveTeamAvatar)
 			protected.POST("/teams/:id/members", handlers.AddTeamMember)
+			protected.PUT("/teams/:id/members/:userId", handlers.UpdateTeamMemberRole)
 			protected.DELETE("/teams/:id/members/:userId", handlers.RemoveTeamMember)
-			protected.DELETE("/teams/:id", handlers.DeleteTeam)
-			protected.PUT("/teams/:id/leader", handlers.UpdateTeamLeader)
+			protected.DELETE("/teams/:id", middleware.RequirePermission(models.PermTeamDelete), handlers.DeleteTeam)
+			protected.PUT("/teams/:id/leader", middleware.RequirePermission(models.PermTeamAssignLeader), handlers.UpdateTeamLeader)
 			protected.GET("/teamsIn", handlers.GetOrganizationTeams)
+			protected.GET("/teams/:id/workflow", handlers.GetTeamWorkflow)
+			protected.PUT("/teams/:id/workflow", handlers.UpdateTeamWorkflow)
+			protected.DELETE("/teams/:id/workflow", handlers.ResetTeamWorkflow)
 
 			protected.GET("/organizations/:id/free-users", handlers.GetFreeUsersInOrganization)
+			protected.GET("/organizations/:id/tree", handlers.GetOrganizationTree)
+			protected.GET("/organizations/:id/audit", middleware.RequirePermission(models.PermAuditView), handlers.GetAuditLog)
+
+			protected.POST("/invites", middleware.RequirePermission(models.PermInviteCreate), handlers.CreateInvite)
+			protected.GET("/invites", middleware.RequirePermission(models.PermInviteManage), handlers.GetInvitesForOrganization)
+			protected.DELETE("/invites/:token", middleware.RequirePermission(models.PermInviteManage), handlers.DeleteInvite)
 
-			protected.POST("/invites", handlers.CreateInvite)
-			protected.GET("/invites", handlers.GetInvitesForOrganization)
-			protected.DELETE("/invites/:token", handlers.DeleteInvite)
+			protected.GET("/webhooks/events", middleware.RequirePermission(models.PermWebhookManage), handlers.GetWebhookEventTypes)
+			protected.GET("/webhooks", middleware.RequirePermission(models.PermWebhookManage), handlers.GetWebhooks)
+			protected.POST("/webhooks", middleware.RequirePermission(models.PermWebhookManage), handlers.CreateWebhook)
+			protected.PUT("/webhooks/:id", middleware.RequirePermission(models.PermWebhookManage), handlers.UpdateWebhook)
+			protected.DELETE("/webhooks/:id", middleware.RequirePermission(models.PermWebhookManage), handlers.DeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", middleware.RequirePermission(models.PermWebhookManage), handlers.GetWebhookDeliveries)
+			protected.POST("/webhooks/:id/deliveries/:deliveryId/replay", middleware.RequirePermission(models.PermWebhookManage), handlers.ReplayWebhookDelivery)
 
-			protected.GET("/potential-leaders", handlers.GetPotentialLeaders)
-			protected.POST("/join/:token", handlers.JoinByInvite)
+			protected.GET("/potential-leaders", middleware.RequirePermission(models.PermTeamAssignLeader), handlers.GetPotentialLeaders)
+			protected.POST("/join/:token", joinLimit, handlers.JoinByInvite)
 
 			protected.GET("/news", handlers.GetNewsFeed)
 			protected.POST("/news", handlers.CreateNews)
@@ -94,12 +172,56 @@ func main() {
 			protected.DELETE("/documents/:id", handlers.DeleteDocument)
 			protected.GET("/documents/download/:id", handlers.DownloadDocument)
 
-			protected.PUT("/users/:id/role", handlers.UpdateUserRole)
+			protected.PUT("/users/:id/role", middleware.RequirePermission(models.PermMemberManageRoles), handlers.UpdateUserRole)
+
+			protected.GET("/permissions", handlers.GetPermissions)
+			protected.GET("/roles", middleware.RequirePermission(models.PermRoleManage), handlers.GetOrganizationRoles)
+			protected.POST("/roles", middleware.RequirePermission(models.PermRoleManage), handlers.CreateOrganizationRole)
+			protected.PUT("/roles/:id", middleware.RequirePermission(models.PermRoleManage), handlers.UpdateOrganizationRole)
+			protected.DELETE("/roles/:id", middleware.RequirePermission(models.PermRoleManage), handlers.DeleteOrganizationRole)
 
 			protected.GET("/tasks", handlers.GetTasks)
+			protected.GET("/tasks/my", handlers.GetMyTasks)
 			protected.POST("/tasks", handlers.CreateTask)
+			protected.GET("/tasks/:id", handlers.GetTask)
 			protected.PUT("/tasks/:id", handlers.UpdateTask)
 			protected.DELETE("/tasks/:id", handlers.DeleteTask)
+			protected.GET("/tasks/:id/comments", handlers.GetTaskComments)
+			protected.POST("/tasks/:id/comments", handlers.CreateTaskComment)
+			protected.PUT("/tasks/:id/comments/:commentId", handlers.UpdateTaskComment)
+			protected.DELETE("/tasks/:id/comments/:commentId", handlers.DeleteTaskComment)
+			protected.GET("/tasks/:id/activity", handlers.GetTaskActivity)
+			protected.GET("/teams/:id/sprints", handlers.GetTeamSprints)
+			protected.POST("/teams/:id/sprints", handlers.CreateSprint)
+			protected.PUT("/sprints/:id", handlers.UpdateSprint)
+			protected.DELETE("/sprints/:id", handlers.DeleteSprint)
+			protected.POST("/sprints/:id/start", handlers.StartSprint)
+			protected.POST("/sprints/:id/close", handlers.CloseSprint)
+			protected.POST("/sprints/:id/rollover", handlers.RolloverSprint)
+			protected.GET("/sprints/:id/burndown", handlers.GetSprintBurndown)
+			protected.GET("/recurring-tasks", handlers.GetTaskRecurrences)
+			protected.POST("/recurring-tasks", handlers.CreateTaskRecurrence)
+			protected.PUT("/recurring-tasks/:id", handlers.UpdateTaskRecurrence)
+			protected.DELETE("/recurring-tasks/:id", handlers.DeleteTaskRecurrence)
+			protected.GET("/tasks/:id/attachments", handlers.GetTaskAttachments)
+			protected.POST("/tasks/:id/attachments", handlers.UploadTaskAttachment)
+			protected.GET("/tasks/:id/attachments/:attachmentId", handlers.DownloadTaskAttachment)
+			protected.DELETE("/tasks/:id/attachments/:attachmentId", handlers.DeleteTaskAttachment)
+			protected.GET("/tasks/:id/time-logs", handlers.GetTaskTimeLogs)
+			protected.POST("/tasks/:id/time-logs", handlers.CreateTaskTimeLog)
+			protected.PUT("/tasks/:id/time-logs/:logId", handlers.UpdateTaskTimeLog)
+			protected.DELETE("/tasks/:id/time-logs/:logId", handlers.DeleteTaskTimeLog)
+			protected.POST("/tasks/:id/timer/start", handlers.StartTaskTimer)
+			protected.POST("/tasks/:id/timer/stop", handlers.StopTaskTimer)
+			protected.GET("/timer", handlers.GetRunningTimer)
+			protected.GET("/reports/time", handlers.GetTimeReport)
+			protected.GET("/tasks/:id/checklist", handlers.GetTaskChecklist)
+			protected.POST("/tasks/:id/checklist", handlers.CreateChecklistItem)
+			protected.PUT("/tasks/:id/checklist/:itemId", handlers.UpdateChecklistItem)
+			protected.DELETE("/tasks/:id/checklist/:itemId", handlers.DeleteChecklistItem)
+			protected.GET("/tasks/:id/blockers", handlers.GetTaskBlockers)
+			protected.POST("/tasks/:id/blockers", handlers.AddTaskBlocker)
+			protected.DELETE("/tasks/:id/blockers/:blockerId", handlers.RemoveTaskBlocker)
 		}
 	}
 
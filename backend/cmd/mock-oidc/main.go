@@ -0,0 +1,128 @@
+// Command mock-oidc is a local OpenID Connect provider for development. It
+// serves discovery and JWKS and mints ID tokens for any user:
+//
+//	curl -d sub=42 -d email=dev@example.com -d email_verified=true localhost:9999/mint
+//
+// The response holds an id_token and a one-time code for the token endpoint.
+// Point the portal at it with OIDC_PROVIDERS=mock, OIDC_MOCK_ISSUER and
+// OIDC_MOCK_CLIENT_ID.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const keyID = "mock-key"
+
+func main() {
+	addr := os.Getenv("MOCK_OIDC_ADDR")
+	if addr == "" {
+		addr = "localhost:9999"
+	}
+	issuer := os.Getenv("MOCK_OIDC_ISSUER")
+	if issuer == "" {
+		issuer = "http://" + addr
+	}
+	clientID := os.Getenv("MOCK_OIDC_CLIENT_ID")
+	if clientID == "" {
+		clientID = "portal"
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	codes := map[string]string{}
+
+	r := gin.Default()
+
+	r.GET("/.well-known/openid-configuration", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"issuer":                                issuer,
+			"jwks_uri":                              issuer + "/jwks",
+			"token_endpoint":                        issuer + "/token",
+			"authorization_endpoint":                issuer + "/authorize",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	r.GET("/jwks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keys": []gin.H{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	// mint takes sub, email, email_verified, name, nonce, aud and
+	// expires_in (seconds, negative for an expired token).
+	r.POST("/mint", func(c *gin.Context) {
+		aud := c.DefaultPostForm("aud", clientID)
+		expiresIn, _ := strconv.Atoi(c.DefaultPostForm("expires_in", "300"))
+		now := time.Now()
+
+		claims := jwt.MapClaims{
+			"iss":            issuer,
+			"sub":            c.DefaultPostForm("sub", uuid.New().String()),
+			"aud":            aud,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Duration(expiresIn) * time.Second).Unix(),
+			"email":          c.PostForm("email"),
+			"email_verified": c.PostForm("email_verified") == "true",
+			"name":           c.PostForm("name"),
+		}
+		if nonce := c.PostForm("nonce"); nonce != "" {
+			claims["nonce"] = nonce
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = keyID
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		code := uuid.New().String()
+		mu.Lock()
+		codes[code] = idToken
+		mu.Unlock()
+		c.JSON(http.StatusOK, gin.H{"id_token": idToken, "code": code})
+	})
+
+	r.POST("/token", func(c *gin.Context) {
+		if c.PostForm("grant_type") != "authorization_code" || c.PostForm("client_id") != clientID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+			return
+		}
+		mu.Lock()
+		idToken, ok := codes[c.PostForm("code")]
+		delete(codes, c.PostForm("code"))
+		mu.Unlock()
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id_token": idToken, "token_type": "Bearer", "expires_in": 300})
+	})
+
+	log.Printf("Mock OIDC provider %s (client id %s)", issuer, clientID)
+	r.Run(addr)
+}
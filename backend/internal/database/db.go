@@ -34,10 +34,73 @@ func Connect() {
 		&models.News{},
 		&models.Document{},
 		&models.Task{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.ExternalIdentity{},
+		&models.RecoveryCode{},
+		&models.APIToken{},
+		&models.APITokenScope{},
+		&models.AuditEvent{},
+		&models.Webhook{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.OrgRole{},
+		&models.RolePermission{},
+		&models.Membership{},
+		&models.TeamMember{},
+		&models.TaskComment{},
+		&models.TaskActivity{},
+		&models.TaskChecklistItem{},
+		&models.TaskDependency{},
+		&models.TaskRecurrence{},
+		&models.TaskTimeLog{},
+		&models.TaskAttachment{},
+		&models.Sprint{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.OverdueDigest{},
+		&models.TeamWorkflowStatus{},
+		&models.TeamWorkflowTransition{},
 	)
 	if err != nil {
 		log.Fatal("Migration failed: ", err)
 	}
 
+	if err := backfillMemberships(db); err != nil {
+		log.Fatal("Membership backfill failed: ", err)
+	}
+	if err := backfillTeamMembers(db); err != nil {
+		log.Fatal("Team member backfill failed: ", err)
+	}
+
 	DB = db
 }
+
+// backfillMemberships creates membership rows for users that joined an
+// organization before memberships existed.
+func backfillMemberships(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO memberships (user_id, organization_id, role, org_role_id, team_id, created_at, updated_at)
+		SELECT u.id, u.organization_id, u.role, u.org_role_id, u.team_id, u.created_at, u.updated_at
+		FROM users u
+		WHERE u.organization_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM memberships m WHERE m.user_id = u.id AND m.organization_id = u.organization_id)`).Error
+}
+
+// backfillTeamMembers moves single-team memberships and team leaders into team_members.
+func backfillTeamMembers(db *gorm.DB) error {
+	if err := db.Exec(`
+		INSERT INTO team_members (team_id, user_id, role, created_at)
+		SELECT t.id, t.leader_id, 'leader', t.created_at
+		FROM teams t
+		WHERE t.leader_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM team_members tm WHERE tm.team_id = t.id AND tm.user_id = t.leader_id)`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+		INSERT INTO team_members (team_id, user_id, role, created_at)
+		SELECT m.team_id, m.user_id, 'member', m.updated_at
+		FROM memberships m
+		JOIN teams t ON t.id = m.team_id AND t.organization_id = m.organization_id
+		WHERE NOT EXISTS (SELECT 1 FROM team_members tm WHERE tm.team_id = m.team_id AND tm.user_id = m.user_id)`).Error
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"corp-portal/internal/models"
+	"sync"
+)
+
+// Event is pushed to the subscribers of an organization. An event without a
+// TeamID is visible to every member; a team event only reaches those who can
+// see the team, hold the ViewAll permission or are listed in UserIDs.
+type Event struct {
+	Type           string
+	OrganizationID uint
+	TeamID         *uint
+	ViewAll        models.Permission
+	UserIDs        []uint
+	Data           interface{}
+}
+
+const (
+	TaskCreated       = "task.created"
+	TaskUpdated       = "task.updated"
+	TaskDeleted       = "task.deleted"
+	NewsCreated       = "news.created"
+	NewsUpdated       = "news.updated"
+	NewsDeleted       = "news.deleted"
+	MemberJoined      = "member.joined"
+	MemberLeft        = "member.left"
+	MemberRemoved     = "member.removed"
+	MemberRoleChanged = "member.role_changed"
+	TeamMemberAdded   = "team.member_added"
+	TeamMemberUpdated = "team.member_updated"
+	TeamMemberRemoved = "team.member_removed"
+	DocumentUploaded  = "document.uploaded"
+	DocumentDeleted   = "document.deleted"
+)
+
+// Types lists every event type, e.g. for webhook subscriptions.
+var Types = []string{
+	TaskCreated, TaskUpdated, TaskDeleted,
+	NewsCreated, NewsUpdated, NewsDeleted,
+	MemberJoined, MemberLeft, MemberRemoved, MemberRoleChanged,
+	TeamMemberAdded, TeamMemberUpdated, TeamMemberRemoved,
+	DocumentUploaded, DocumentDeleted,
+}
+
+// TeamScopedTypes maps the event types that can carry team-private data to
+// the permission that lets a member see them for every team.
+var TeamScopedTypes = map[string]models.Permission{
+	TaskCreated:      models.PermTaskViewAll,
+	TaskUpdated:      models.PermTaskViewAll,
+	TaskDeleted:      models.PermTaskViewAll,
+	NewsCreated:      models.PermNewsViewAll,
+	NewsUpdated:      models.PermNewsViewAll,
+	NewsDeleted:      models.PermNewsViewAll,
+	DocumentUploaded: models.PermDocumentViewAll,
+	DocumentDeleted:  models.PermDocumentViewAll,
+}
+
+// Sink receives every published event, for consumers outside the process
+// such as webhooks. Sinks run on the publishing goroutine.
+type Sink func(Event)
+
+const bufferSize = 32
+
+// Subscription receives the events of one organization. C is closed when the
+// subscriber falls too far behind; clients are expected to reconnect and
+// refetch.
+type Subscription struct {
+	OrganizationID uint
+	C              <-chan Event
+	ch             chan Event
+}
+
+var hub = struct {
+	sync.Mutex
+	subscribers map[uint]map[*Subscription]bool
+	sinks       []Sink
+}{subscribers: make(map[uint]map[*Subscription]bool)}
+
+func AddSink(sink Sink) {
+	hub.Lock()
+	hub.sinks = append(hub.sinks, sink)
+	hub.Unlock()
+}
+
+func Subscribe(orgID uint) *Subscription {
+	ch := make(chan Event, bufferSize)
+	sub := &Subscription{OrganizationID: orgID, C: ch, ch: ch}
+
+	hub.Lock()
+	if hub.subscribers[orgID] == nil {
+		hub.subscribers[orgID] = make(map[*Subscription]bool)
+	}
+	hub.subscribers[orgID][sub] = true
+	hub.Unlock()
+	return sub
+}
+
+func (s *Subscription) Close() {
+	hub.Lock()
+	defer hub.Unlock()
+	drop(s)
+}
+
+func drop(s *Subscription) {
+	subs := hub.subscribers[s.OrganizationID]
+	if !subs[s] {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(hub.subscribers, s.OrganizationID)
+	}
+	close(s.ch)
+}
+
+// Publish hands the event to every subscriber of its organization without
+// blocking, then to the sinks. Visibility is checked by the receiving stream.
+func Publish(event Event) {
+	hub.Lock()
+	for sub := range hub.subscribers[event.OrganizationID] {
+		select {
+		case sub.ch <- event:
+		default:
+			drop(sub)
+		}
+	}
+	sinks := hub.sinks
+	hub.Unlock()
+
+	for _, sink := range sinks {
+		sink(event)
+	}
+}
+
+// TaskEvent builds an event for the task's team that also reaches its
+// assignee and any previous assignees passed in.
+func TaskEvent(eventType string, task *models.Task, data interface{}, previousAssignees ...*uint) Event {
+	teamID := task.TeamID
+	event := Event{
+		Type:           eventType,
+		OrganizationID: task.OrganizationID,
+		TeamID:         &teamID,
+		ViewAll:        models.PermTaskViewAll,
+		Data:           data,
+	}
+	for _, id := range append(previousAssignees, task.AssigneeID) {
+		if id != nil {
+			event.UserIDs = append(event.UserIDs, *id)
+		}
+	}
+	return event
+}
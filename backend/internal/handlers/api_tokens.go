@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxAPITokensPerUser = 50
+
+type CreateAPITokenInput struct {
+	Name   string            `json:"name" binding:"required,max=100"`
+	Scopes []models.APIScope `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays of zero creates a token that does not expire.
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+func buildAPITokenResponse(token *models.APIToken) models.APITokenResponse {
+	response := models.APITokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     make([]models.APIScope, len(token.Scopes)),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		CreatedAt:  token.CreatedAt,
+	}
+	for i, s := range token.Scopes {
+		response.Scopes[i] = s.Scope
+	}
+	return response
+}
+
+func GetAPIScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AllAPIScopes)
+}
+
+func GetMyAPITokens(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var tokens []models.APIToken
+	if err := database.DB.Preload("Scopes").
+		Where("user_id = ? AND revoked_at IS NULL", principal.ID()).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	response := make([]models.APITokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = buildAPITokenResponse(&tokens[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateAPIToken returns the token value once; only its hash is kept.
+func CreateAPIToken(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input CreateAPITokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	seen := map[models.APIScope]bool{}
+	for _, scope := range input.Scopes {
+		if !models.IsValidAPIScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + string(scope)})
+			return
+		}
+		seen[scope] = true
+	}
+
+	var count int64
+	database.DB.Model(&models.APIToken{}).Where("user_id = ? AND revoked_at IS NULL", principal.ID()).Count(&count)
+	if count >= maxAPITokensPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many API tokens, revoke unused ones first"})
+		return
+	}
+
+	secret, _, err := utils.GenerateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	raw := middleware.APITokenPrefix + secret
+
+	token := models.APIToken{
+		UserID:    principal.ID(),
+		Name:      input.Name,
+		Prefix:    raw[:len(middleware.APITokenPrefix)+8],
+		TokenHash: utils.HashToken(raw),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	for _, scope := range models.AllAPIScopes {
+		if seen[scope] {
+			token.Scopes = append(token.Scopes, models.APITokenScope{Scope: scope})
+		}
+	}
+
+	if err := database.DB.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     raw,
+		"api_token": buildAPITokenResponse(&token),
+	})
+}
+
+func RevokeAPIToken(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	result := database.DB.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), principal.ID()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
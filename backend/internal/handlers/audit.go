@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+	maxAuditExportRows   = 50000
+)
+
+// recordAudit appends an audit event for the current request. before and
+// after hold the fields the action changed; either may be nil.
+func recordAudit(c *gin.Context, db *gorm.DB, orgID uint, action models.AuditAction, targetType string, targetID uint, before, after gin.H) {
+	principal := middleware.GetPrincipal(c)
+
+	actorID := principal.ID()
+	event := models.AuditEvent{
+		OrganizationID: orgID,
+		ActorID:        &actorID,
+		ActorEmail:     principal.User.Email,
+		Action:         action,
+		TargetType:     targetType,
+		TargetID:       targetID,
+		Before:         auditJSON(before),
+		After:          auditJSON(after),
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	if tokenID := c.GetUint("apiTokenID"); tokenID != 0 {
+		event.APITokenID = &tokenID
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("Audit: failed to record %s on %s %d: %v", action, targetType, targetID, err)
+	}
+}
+
+func auditJSON(fields gin.H) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// GetAuditLog lists the organization's audit events, newest first. Filters:
+// action (comma separated), actor_id, target_type, target_id, and from/to as
+// YYYY-MM-DD. Pages use limit and the cursor from X-Next-Cursor.
+// format=csv exports every matching event instead.
+func GetAuditLog(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || !principal.InOrganization(uint(orgID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	db := database.DB.Model(&models.AuditEvent{}).Where("organization_id = ?", orgID)
+
+	if value := c.Query("action"); value != "" {
+		db = db.Where("action IN ?", strings.Split(value, ","))
+	}
+	if value := c.Query("actor_id"); value != "" {
+		db = db.Where("actor_id = ?", value)
+	}
+	if value := c.Query("target_type"); value != "" {
+		db = db.Where("target_type = ?", value)
+	}
+	if value := c.Query("target_id"); value != "" {
+		db = db.Where("target_id = ?", value)
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected YYYY-MM-DD"})
+			return
+		}
+		db = db.Where("created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected YYYY-MM-DD"})
+			return
+		}
+		db = db.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	if c.Query("format") == "csv" {
+		exportAuditCSV(c, db, uint(orgID))
+		return
+	}
+
+	limit := defaultAuditPageSize
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxAuditPageSize {
+			limit = maxAuditPageSize
+		}
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		db = db.Where("id < ?", cursor)
+	}
+
+	var auditEvents []models.AuditEvent
+	if err := db.Order("id desc").Limit(limit + 1).Find(&auditEvents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	if len(auditEvents) > limit {
+		auditEvents = auditEvents[:limit]
+		c.Header("X-Next-Cursor", strconv.FormatUint(uint64(auditEvents[limit-1].ID), 10))
+	}
+	c.JSON(http.StatusOK, auditEvents)
+}
+
+func exportAuditCSV(c *gin.Context, db *gorm.DB, orgID uint) {
+	var auditEvents []models.AuditEvent
+	if err := db.Order("id desc").Limit(maxAuditExportRows).Find(&auditEvents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%d-%s.csv", orgID, time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "created_at", "actor_id", "actor_email", "api_token_id", "action", "target_type", "target_id", "before", "after", "ip", "user_agent"})
+	for _, e := range auditEvents {
+		w.Write([]string{
+			strconv.FormatUint(uint64(e.ID), 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			formatIDRef(e.ActorID),
+			csvSafe(e.ActorEmail),
+			formatIDRef(e.APITokenID),
+			string(e.Action),
+			e.TargetType,
+			strconv.FormatUint(uint64(e.TargetID), 10),
+			csvSafe(e.Before),
+			csvSafe(e.After),
+			e.IP,
+			csvSafe(e.UserAgent),
+		})
+	}
+	w.Flush()
+}
+
+// csvSafe keeps spreadsheet apps from evaluating user-controlled cells as
+// formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
-	"corp-portal/internal/utils"
+	"corp-portal/internal/ratelimit"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -21,6 +26,44 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+var (
+	// loginAccountRule caps login attempts per email from all IPs.
+	loginAccountRule = ratelimit.Rule{Name: "login-account", Limit: 20, Window: 15 * time.Minute}
+	// loginLockout locks an email for a minute after five failed passwords
+	// or second factors within an hour, doubling up to an hour.
+	loginLockout = ratelimit.Lockout{Name: "login", Threshold: 5, Window: time.Hour, Base: time.Minute, Max: time.Hour}
+)
+
+func loginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginAllowed rejects the attempt with 429 when the account is locked
+// or over its attempt limit.
+func checkLoginAllowed(c *gin.Context, email string) bool {
+	key := loginKey(email)
+	if lock := loginLockout.Locked(key); lock > 0 {
+		ratelimit.LogEvent(c, "login_locked", "email=%s", key)
+		ratelimit.TooManyRequests(c, lock, "Too many failed login attempts, try again later")
+		return false
+	}
+	if ok, retryAfter := loginAccountRule.Allow(key); !ok {
+		ratelimit.LogEvent(c, "rate_limited", "rule=%s email=%s", loginAccountRule.Name, key)
+		ratelimit.TooManyRequests(c, retryAfter, "Too many login attempts, try again later")
+		return false
+	}
+	return true
+}
+
+func recordLoginFailure(c *gin.Context, email string) {
+	key := loginKey(email)
+	if lock := loginLockout.Fail(key); lock > 0 {
+		ratelimit.LogEvent(c, "account_locked", "email=%s duration=%s", key, lock)
+		return
+	}
+	ratelimit.LogEvent(c, "login_failed", "email=%s", key)
+}
+
 func Register(c *gin.Context) {
 	var input RegisterInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -45,6 +88,9 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User with this email already exists"})
 		return
 	}
+	if err := sendVerificationEmail(&user, requestLocale(c, "")); err != nil {
+		log.Printf("Verification email for user %d failed: %v", user.ID, err)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful"})
 }
@@ -56,58 +102,45 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if !checkLoginAllowed(c, input.Email) {
+		return
+	}
+
 	var user models.User
 	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		recordLoginFailure(c, input.Email)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		recordLoginFailure(c, input.Email)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	token, err := utils.GenerateToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":        user.ID,
-			"full_name": user.FullName,
-			"role":      user.Role,
-		},
-	})
+	signIn(c, user)
 }
 
 func GetProfile(c *gin.Context) {
-	userID, _ := c.Get("userID")
+	principal := middleware.GetPrincipal(c)
 
-	var user models.User
-	if err := database.DB.Preload("Organization").First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
+	response := buildUserProfileResponse(&principal.User)
+	for _, perm := range models.AllPermissions {
+		if principal.Can(perm) {
+			response.Permissions = append(response.Permissions, perm)
+		}
 	}
-	response := buildUserProfileResponse(&user)
 	c.JSON(http.StatusOK, response)
 }
 
 type UpdateRoleInput struct {
-	Role int `json:"role" binding:"required"`
+	Role   *int  `json:"role"`
+	RoleID *uint `json:"role_id"`
 }
 
 func UpdateUserRole(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
-	requestorID := c.MustGet("userID").(uint)
-
-	if requestorRole < models.RoleSuperAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only Super Admin can manage roles"})
-		return
-	}
-
+	principal := middleware.GetPrincipal(c)
 	targetUserID := c.Param("id")
 
 	var input UpdateRoleInput
@@ -116,8 +149,8 @@ func UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	if input.Role < 1 || input.Role > 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Allowed: 1 (User), 2 (Manager), 3 (Admin)"})
+	if (input.Role == nil) == (input.RoleID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either role or role_id"})
 		return
 	}
 
@@ -126,26 +159,75 @@ func UpdateUserRole(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-	if targetUser.ID == requestorID {
+	if targetUser.ID == principal.ID() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change your own role here"})
 		return
 	}
 
-	if targetUser.Role == models.RoleSuperAdmin {
+	membership, ok := membershipInActiveOrganization(principal, targetUser.ID)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User belongs to another organization"})
+		return
+	}
+
+	if membership.Role == models.RoleSuperAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot demote another Super Admin"})
 		return
 	}
+	if membership.Role > principal.Role {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot change the role of a member ranked above you"})
+		return
+	}
 
-	requester, _ := getUserFromContext(c)
-	if requester.OrganizationID == nil || targetUser.OrganizationID == nil || *requester.OrganizationID != *targetUser.OrganizationID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "User belongs to another organization"})
+	updates := map[string]interface{}{}
+	var newRole models.Role
+	if input.RoleID != nil {
+		var orgRole models.OrgRole
+		if err := database.DB.Preload("Permissions").
+			Where("id = ? AND organization_id = ?", *input.RoleID, membership.OrganizationID).
+			First(&orgRole).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+			return
+		}
+		perms := make([]models.Permission, len(orgRole.Permissions))
+		for i, rp := range orgRole.Permissions {
+			perms[i] = rp.Permission
+		}
+		if perm, found := ungrantablePermission(principal, perms); found {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant a permission you do not have: " + string(perm)})
+			return
+		}
+		newRole = orgRole.BaseRole
+		if orgRole.IsSystem {
+			updates["org_role_id"] = nil
+		} else {
+			updates["org_role_id"] = orgRole.ID
+		}
+	} else {
+		newRole = models.Role(*input.Role)
+		updates["org_role_id"] = nil
+	}
+
+	if newRole < models.RoleUser || newRole > models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Allowed: 0 (User), 1 (Employee), 2 (Manager), 3 (Admin)"})
+		return
+	}
+	if newRole > principal.Role {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot grant a role above your own"})
 		return
 	}
+	updates["role"] = newRole
+	previousRole, previousOrgRoleID := membership.Role, membership.OrgRoleID
 
-	if err := database.DB.Model(&targetUser).Update("role", input.Role).Error; err != nil {
+	if err := updateMembership(database.DB, &membership, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
 		return
 	}
+	revokeUserSessions(database.DB, targetUser.ID)
+	recordAudit(c, database.DB, membership.OrganizationID, models.AuditMemberRoleChanged, "user", targetUser.ID,
+		gin.H{"role": previousRole, "org_role_id": previousOrgRoleID},
+		gin.H{"role": newRole, "org_role_id": updates["org_role_id"]})
+	publishMemberEvent(events.MemberRoleChanged, membership.OrganizationID, targetUser.ID, nil, newRole)
 
-	c.JSON(http.StatusOK, gin.H{"message": "User role updated", "new_role": input.Role})
+	c.JSON(http.StatusOK, gin.H{"message": "User role updated", "new_role": newRole, "role_id": updates["org_role_id"]})
 }
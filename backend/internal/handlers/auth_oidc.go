@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/oidc"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginInput carries either an ID token obtained by the frontend or an
+// authorization code for the backend to exchange.
+type OIDCLoginInput struct {
+	IDToken      string `json:"id_token"`
+	Code         string `json:"code"`
+	RedirectURI  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+var (
+	errIdentityNoEmail         = errors.New("The identity provider did not share an email")
+	errIdentityUnverifiedEmail = errors.New("The identity provider has not verified this email")
+)
+
+func GoogleLogin(c *gin.Context) {
+	oidcLogin(c, "google")
+}
+
+func OIDCLogin(c *gin.Context) {
+	oidcLogin(c, c.Param("provider"))
+}
+
+// GetAuthProviders lists the configured identity providers for login buttons.
+func GetAuthProviders(c *gin.Context) {
+	response := []gin.H{}
+	for _, provider := range oidc.List() {
+		response = append(response, gin.H{
+			"name":         provider.Name,
+			"display_name": provider.DisplayName,
+			"issuer":       provider.Issuer,
+			"client_id":    provider.ClientID,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func oidcLogin(c *gin.Context, name string) {
+	provider, ok := oidc.Get(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	var input OIDCLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.IDToken == "" && input.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id_token or code is required"})
+		return
+	}
+
+	rawIDToken := input.IDToken
+	if rawIDToken == "" {
+		var err error
+		rawIDToken, err = provider.Exchange(c.Request.Context(), input.Code, input.RedirectURI, input.CodeVerifier)
+		if err != nil {
+			log.Printf("OIDC %s: code exchange failed: %v", provider.Name, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange authorization code"})
+			return
+		}
+	}
+
+	claims, err := provider.Verify(c.Request.Context(), rawIDToken, input.Nonce)
+	if err != nil {
+		log.Printf("OIDC %s: rejected ID token: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+
+	var user *models.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		user, err = findOrLinkExternalUser(tx, provider, claims)
+		return err
+	})
+	if errors.Is(err, errIdentityNoEmail) || errors.Is(err, errIdentityUnverifiedEmail) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not sign in"})
+		return
+	}
+
+	signIn(c, *user)
+}
+
+// findOrLinkExternalUser returns the account linked to the provider subject.
+// On first login the identity is linked to the account with the same email,
+// or a new account is created, but only if the provider vouches for the email.
+func findOrLinkExternalUser(tx *gorm.DB, provider *oidc.Provider, claims *oidc.Claims) (*models.User, error) {
+	now := time.Now()
+
+	var identity models.ExternalIdentity
+	err := tx.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := tx.First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": now}).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, errIdentityNoEmail
+	}
+	if !bool(claims.EmailVerified) && !provider.TrustEmail {
+		return nil, errIdentityUnverifiedEmail
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(uuid.New().String()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = tx.Where("email = ?", claims.Email).First(&user).Error
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			// Whoever registered the unverified account may not own the
+			// address, so its password and sessions stop working.
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"password":          string(hashedPassword),
+				"email_verified_at": now,
+			}).Error; err != nil {
+				return nil, err
+			}
+			if err := revokeUserSessions(tx, user.ID); err != nil {
+				return nil, err
+			}
+			if err := middleware.BumpPrincipalVersion(tx, user.ID); err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		fullName := claims.Name
+		if fullName == "" {
+			fullName = claims.PreferredUsername
+		}
+		if fullName == "" {
+			fullName = claims.Email
+		}
+		user = models.User{
+			Email:           claims.Email,
+			Password:        string(hashedPassword),
+			FullName:        fullName,
+			AvatarURL:       claims.Picture,
+			Role:            models.RoleUser,
+			EmailVerifiedAt: &now,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	identity = models.ExternalIdentity{
+		UserID:      user.ID,
+		Provider:    provider.Name,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: now,
+	}
+	if err := tx.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func GetMyIdentities(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var identities []models.ExternalIdentity
+	if err := database.DB.Where("user_id = ?", principal.ID()).Order("created_at asc").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+func UnlinkIdentity(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	result := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), principal.ID()).Delete(&models.ExternalIdentity{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
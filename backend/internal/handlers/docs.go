@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
 	"net/http"
 	"net/url"
@@ -16,31 +18,25 @@ import (
 )
 
 func GetDocuments(c *gin.Context) {
-	userID, _ := c.Get("userID")
+	principal := middleware.GetPrincipal(c)
 	searchQuery := c.Query("search")
 	tagIDsParam := c.Query("tag_ids")
 	authorIDsParam := c.Query("author_ids")
 	teamIDsParam := c.Query("team_ids")
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusOK, []models.Document{})
 		return
 	}
 
 	db := database.DB.Model(&models.Document{}).
-		Where("documents.organization_id = ?", *user.OrganizationID).
+		Where("documents.organization_id = ?", *principal.OrganizationID).
 		Preload("Author").
 		Preload("Tags")
 
-	if user.Role < models.RoleAdmin {
-		if user.TeamID != nil {
-			db = db.Where("documents.team_id IS NULL OR documents.team_id = ?", *user.TeamID)
+	if !principal.Can(models.PermDocumentViewAll) {
+		if len(principal.VisibleTeamIDs) > 0 {
+			db = db.Where("documents.team_id IS NULL OR documents.team_id IN ?", principal.VisibleTeamIDs)
 		} else {
 			db = db.Where("documents.team_id IS NULL")
 		}
@@ -81,8 +77,7 @@ func GetDocuments(c *gin.Context) {
 }
 
 func UploadDocument(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -94,6 +89,10 @@ func UploadDocument(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
 		return
 	}
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in an organization"})
+		return
+	}
 	file, err := c.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
@@ -107,11 +106,6 @@ func UploadDocument(c *gin.Context) {
 		return
 	}
 	fileURL := "http://localhost:8080/" + dst
-	var user models.User
-	if err := database.DB.Preload("Team").First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
 	var tags []models.Tag
 	if tagsIDsStr != "" {
 		idStrings := strings.Split(tagsIDsStr, ",")
@@ -132,37 +126,48 @@ func UploadDocument(c *gin.Context) {
 		FileURL:        fileURL,
 		OriginalName:   file.Filename,
 		Tags:           tags,
-		OrganizationID: *user.OrganizationID,
-		AuthorID:       userID,
+		OrganizationID: *principal.OrganizationID,
+		AuthorID:       principal.ID(),
 		CreatedAt:      time.Now(),
 	}
 	forTeam, _ := strconv.ParseBool(forTeamStr)
 	if forTeam {
-		if role >= 3 && targetTeamIDStr != "" {
+		if principal.Can(models.PermDocumentUploadAnyTeam) && targetTeamIDStr != "" {
 			tID, _ := strconv.ParseUint(targetTeamIDStr, 10, 32)
 			val := uint(tID)
+
+			var checkTeam models.Team
+			if err := database.DB.Where("id = ? AND organization_id = ?", val, *principal.OrganizationID).First(&checkTeam).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target team for your organization"})
+				return
+			}
 			doc.TeamID = &val
 		} else {
-			if user.TeamID == nil {
+			teamID := principal.TeamID
+			if tID, err := strconv.ParseUint(targetTeamIDStr, 10, 32); err == nil && principal.InTeam(uint(tID)) {
+				val := uint(tID)
+				teamID = &val
+			}
+			if teamID == nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "You are not in a team"})
 				return
 			}
-			doc.TeamID = user.TeamID
+			doc.TeamID = teamID
 		}
 	}
-	doc.Author = user
+	doc.Author = principal.User
 
 	if err := database.DB.Create(&doc).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to DB"})
 		return
 	}
+	events.Publish(documentEvent(events.DocumentUploaded, &doc, doc))
 	c.JSON(http.StatusCreated, doc)
 }
 
 func DeleteDocument(c *gin.Context) {
 	docID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var doc models.Document
 	if err := database.DB.First(&doc, docID).Error; err != nil {
@@ -170,12 +175,16 @@ func DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	if doc.AuthorID != userID && role < models.RoleAdmin {
+	canModerate := principal.InOrganization(doc.OrganizationID) && principal.Can(models.PermDocumentModerate)
+	if doc.AuthorID != principal.ID() && !canModerate {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
 		return
 	}
 	removeDocFileFromURL(doc.FileURL)
 	database.DB.Delete(&doc)
+	recordAudit(c, database.DB, doc.OrganizationID, models.AuditDocumentDeleted, "document", doc.ID,
+		gin.H{"title": doc.Title, "original_name": doc.OriginalName, "author_id": doc.AuthorID, "team_id": doc.TeamID}, nil)
+	events.Publish(documentEvent(events.DocumentDeleted, &doc, gin.H{"id": doc.ID, "team_id": doc.TeamID}))
 
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const eventsHeartbeat = 25 * time.Second
+
+// canSeeEvent mirrors the feed rules: team events need the team to be visible
+// or the event's view-all permission, organization events reach everyone.
+func canSeeEvent(principal *middleware.Principal, event *events.Event) bool {
+	if event.TeamID == nil {
+		return true
+	}
+	for _, id := range event.UserIDs {
+		if id == principal.ID() {
+			return true
+		}
+	}
+	return (event.ViewAll != "" && principal.Can(event.ViewAll)) || principal.CanSeeTeam(*event.TeamID)
+}
+
+func newsEvent(eventType string, news *models.News, data interface{}) events.Event {
+	return events.Event{
+		Type:           eventType,
+		OrganizationID: news.OrganizationID,
+		TeamID:         news.TeamID,
+		ViewAll:        models.PermNewsViewAll,
+		Data:           data,
+	}
+}
+
+func documentEvent(eventType string, doc *models.Document, data interface{}) events.Event {
+	return events.Event{
+		Type:           eventType,
+		OrganizationID: doc.OrganizationID,
+		TeamID:         doc.TeamID,
+		ViewAll:        models.PermDocumentViewAll,
+		Data:           data,
+	}
+}
+
+func publishMemberEvent(eventType string, orgID, userID uint, teamID *uint, role interface{}) {
+	data := gin.H{"user_id": userID}
+	if teamID != nil {
+		data["team_id"] = *teamID
+	}
+	if role != nil {
+		data["role"] = role
+	}
+	events.Publish(events.Event{Type: eventType, OrganizationID: orgID, Data: data})
+}
+
+// StreamEvents pushes the caller's organization events as Server-Sent Events.
+// The stream ends when the session is revoked or the caller leaves or switches
+// the organization; clients reconnect and refetch.
+func StreamEvents(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are not in an organization"})
+		return
+	}
+	orgID := *principal.OrganizationID
+	userID := principal.ID()
+	sessionID := c.MustGet("sessionID").(uint)
+
+	// Team and permission changes apply to an open stream, so the principal
+	// is reloaded for every event.
+	current := func() (*middleware.Principal, bool) {
+		if !middleware.SessionActive(userID, sessionID) {
+			return nil, false
+		}
+		p, err := middleware.LoadPrincipal(userID)
+		if err != nil || !p.InOrganization(orgID) {
+			return nil, false
+		}
+		return p, true
+	}
+
+	sub := events.Subscribe(orgID)
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"organization_id": orgID})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			p, ok := current()
+			if !ok {
+				return false
+			}
+			if canSeeEvent(p, &event) {
+				c.SSEvent(event.Type, event.Data)
+			}
+			return true
+		case <-heartbeat.C:
+			if _, ok := current(); !ok {
+				return false
+			}
+			io.WriteString(w, ": ping\n\n")
+			return true
+		}
+	})
+}
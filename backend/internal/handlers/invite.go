@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/mailer"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
+	"log"
 	"net/http"
 	"time"
 
@@ -11,32 +15,29 @@ import (
 	"gorm.io/gorm"
 )
 
-func getUserFromContext(c *gin.Context) (models.User, error) {
-	userID := c.MustGet("userID").(uint)
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		return user, err
-	}
-	return user, nil
-}
-
 type CreateInviteInput struct {
 	ExpiresInHours int `json:"expires_in_hours" binding:"required,min=1"`
 	MaxUses        int `json:"max_uses" binding:"required,min=1"`
+	// Email, when set, gets the invite link in Locale (the mail default otherwise).
+	Email  string `json:"email" binding:"omitempty,email"`
+	Locale string `json:"locale"`
 }
 
-func CreateInvite(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-		return
-	}
+func sendInviteEmail(principal *middleware.Principal, invite *models.Invite, locale string) error {
+	var org models.Organization
+	if err := database.DB.First(&org, invite.OrganizationID).Error; err != nil {
+		return err
+	}
+	return mailer.SendTemplate(invite.Email, locale, "invite", gin.H{
+		"OrganizationName": org.Name,
+		"InviterName":      principal.User.FullName,
+		"Link":             mailer.Link("/join?token=" + invite.Token),
+		"ExpiresAt":        invite.ExpiresAt.Format("2006-01-02 15:04 MST"),
+	})
+}
 
-	user, err := getUserFromContext(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return
-	}
+func CreateInvite(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
 
 	var input CreateInviteInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -44,18 +45,19 @@ func CreateInvite(c *gin.Context) {
 		return
 	}
 
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in an organization"})
 		return
 	}
 
 	invite := models.Invite{
 		Token:          uuid.New().String(),
-		OrganizationID: *user.OrganizationID,
-		CreatedByID:    user.ID,
+		OrganizationID: *principal.OrganizationID,
+		CreatedByID:    principal.ID(),
 		ExpiresAt:      time.Now().Add(time.Hour * time.Duration(input.ExpiresInHours)),
 		MaxUses:        input.MaxUses,
 		Uses:           0,
+		Email:          input.Email,
 	}
 
 	if err := database.DB.Create(&invite).Error; err != nil {
@@ -63,47 +65,50 @@ func CreateInvite(c *gin.Context) {
 		return
 	}
 
+	if invite.Email != "" {
+		if err := sendInviteEmail(principal, &invite, input.Locale); err != nil {
+			log.Println("Invite email failed:", err)
+			database.DB.Unscoped().Delete(&invite)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invite email"})
+			return
+		}
+	}
+	recordAudit(c, database.DB, invite.OrganizationID, models.AuditInviteCreated, "invite", invite.ID, nil,
+		gin.H{"max_uses": invite.MaxUses, "expires_at": invite.ExpiresAt, "email": invite.Email})
+
 	c.JSON(http.StatusCreated, invite)
 }
 
 func GetInvitesForOrganization(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
-	user, err := getUserFromContext(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return
-	}
-
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusOK, []models.Invite{})
 		return
 	}
 
 	var invites []models.Invite
-	database.DB.Preload("CreatedBy").Where("organization_id = ?", user.OrganizationID).Find(&invites)
+	database.DB.Preload("CreatedBy").Where("organization_id = ?", principal.OrganizationID).Find(&invites)
 
-	print("INVITES")
-	print(invites)
 	c.JSON(http.StatusOK, invites)
 }
 
 func DeleteInvite(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+	principal := middleware.GetPrincipal(c)
+	token := c.Param("token")
+
+	var invite models.Invite
+	if err := database.DB.Where("token = ? AND organization_id = ?", token, principal.OrganizationID).First(&invite).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
 		return
 	}
-	token := c.Param("token")
-	result := database.DB.Where("token = ?", token).Delete(&models.Invite{})
+	result := database.DB.Delete(&invite)
 	if result.Error != nil || result.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
 		return
 	}
+	recordAudit(c, database.DB, invite.OrganizationID, models.AuditInviteDeleted, "invite", invite.ID,
+		gin.H{"max_uses": invite.MaxUses, "uses": invite.Uses, "expires_at": invite.ExpiresAt, "email": invite.Email}, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Invite deleted"})
 }
@@ -112,6 +117,11 @@ func JoinByInvite(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 	token := c.Param("token")
 
+	if emailVerificationRequired() && middleware.GetPrincipal(c).User.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email before joining an organization"})
+		return
+	}
+
 	tx := database.DB.Begin()
 
 	var invite models.Invite
@@ -132,13 +142,31 @@ func JoinByInvite(c *gin.Context) {
 		return
 	}
 
-	err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
-		"organization_id": invite.OrganizationID,
-		"team_id":         gorm.Expr("NULL"),
-		"role":            models.RoleUser,
-	}).Error
+	var existingCount int64
+	tx.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ?", userID, invite.OrganizationID).Count(&existingCount)
+	if existingCount > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this organization"})
+		return
+	}
 
-	if err != nil {
+	membership := models.Membership{
+		UserID:         userID,
+		OrganizationID: invite.OrganizationID,
+		Role:           models.RoleUser,
+	}
+	if err := tx.Create(&membership).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("organization_id", invite.OrganizationID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	if err := syncActiveMembership(tx, userID); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
@@ -151,28 +179,19 @@ func JoinByInvite(c *gin.Context) {
 	}
 
 	tx.Commit()
+	publishMemberEvent(events.MemberJoined, invite.OrganizationID, userID, nil, membership.Role)
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully joined organization"})
 }
 
 func GetPotentialLeaders(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-		return
-	}
-
-	user, err := getUserFromContext(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
 	currentTeamID := c.Query("team_id")
 
 	var potentialLeaders []models.User
 
 	query := database.DB.Select("id", "full_name", "email", "avatar_url").
-		Where("organization_id = ?", user.OrganizationID)
+		Where("id IN (SELECT user_id FROM memberships WHERE organization_id = ?)", principal.OrganizationID)
 
 	if currentTeamID != "" {
 		query = query.Where(
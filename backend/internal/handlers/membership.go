@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func findMembership(db *gorm.DB, userID, orgID uint) (models.Membership, error) {
+	var membership models.Membership
+	err := db.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&membership).Error
+	return membership, err
+}
+
+func isMember(userID, orgID uint) bool {
+	var count int64
+	database.DB.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ?", userID, orgID).Count(&count)
+	return count > 0
+}
+
+// syncActiveMembership mirrors the membership of the user's active organization
+// onto the users row. If that membership is gone, another one becomes active.
+func syncActiveMembership(db *gorm.DB, userID uint) error {
+	var user models.User
+	if err := db.Select("id", "organization_id").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	var membership models.Membership
+	found := false
+	if user.OrganizationID != nil {
+		err := db.Where("user_id = ? AND organization_id = ?", userID, *user.OrganizationID).First(&membership).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		found = err == nil
+	}
+	if !found {
+		err := db.Where("user_id = ?", userID).Order("updated_at desc").First(&membership).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		found = err == nil
+	}
+
+	updates := map[string]interface{}{
+		"organization_id": nil,
+		"team_id":         nil,
+		"role":            models.RoleUser,
+		"org_role_id":     nil,
+	}
+	if found {
+		updates["organization_id"] = membership.OrganizationID
+		updates["team_id"] = membership.TeamID
+		updates["role"] = membership.Role
+		updates["org_role_id"] = membership.OrgRoleID
+	}
+
+	if err := db.Model(&models.User{ID: userID}).Updates(updates).Error; err != nil {
+		return err
+	}
+	return middleware.BumpPrincipalVersion(db, userID)
+}
+
+func updateMembership(db *gorm.DB, membership *models.Membership, updates map[string]interface{}) error {
+	if err := db.Model(membership).Updates(updates).Error; err != nil {
+		return err
+	}
+	return syncActiveMembership(db, membership.UserID)
+}
+
+func removeMembership(db *gorm.DB, userID, orgID uint) error {
+	if err := db.Where("user_id = ? AND team_id IN (SELECT id FROM teams WHERE organization_id = ?)", userID, orgID).
+		Delete(&models.TeamMember{}).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Team{}).Where("leader_id = ? AND organization_id = ?", userID, orgID).
+		Update("leader_id", nil).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ? AND organization_id = ?", userID, orgID).Delete(&models.Membership{}).Error; err != nil {
+		return err
+	}
+	return syncActiveMembership(db, userID)
+}
+
+// organizationMembers returns the members of an organization with role and team
+// taken from their membership there rather than from their active organization.
+func organizationMembers(db *gorm.DB, orgID uint, scopes ...func(*gorm.DB) *gorm.DB) ([]models.User, error) {
+	var memberships []models.Membership
+	if err := db.Preload("User").Scopes(scopes...).
+		Where("memberships.organization_id = ?", orgID).
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, 0, len(memberships))
+	for _, m := range memberships {
+		if m.User == nil {
+			continue
+		}
+		users = append(users, applyMembership(*m.User, m))
+	}
+	return users, nil
+}
+
+func applyMembership(user models.User, membership models.Membership) models.User {
+	orgID := membership.OrganizationID
+	user.OrganizationID = &orgID
+	user.Role = membership.Role
+	user.OrgRoleID = membership.OrgRoleID
+	user.TeamID = membership.TeamID
+	return user
+}
+
+func GetMyMemberships(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var memberships []models.Membership
+	if err := database.DB.Preload("Organization").
+		Where("user_id = ?", principal.ID()).
+		Order("created_at asc").
+		Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memberships"})
+		return
+	}
+
+	response := make([]models.MembershipResponse, len(memberships))
+	for i, m := range memberships {
+		response[i] = models.MembershipResponse{
+			OrganizationID: m.OrganizationID,
+			Role:           m.Role,
+			OrgRoleID:      m.OrgRoleID,
+			TeamID:         m.TeamID,
+			IsActive:       principal.InOrganization(m.OrganizationID),
+			JoinedAt:       m.CreatedAt,
+		}
+		if m.Organization != nil {
+			response[i].Organization = &models.OrganizationResponse{
+				ID:          m.Organization.ID,
+				Name:        m.Organization.Name,
+				Description: m.Organization.Description,
+				AvatarURL:   m.Organization.AvatarURL,
+				OwnerID:     m.Organization.OwnerID,
+				CreatedAt:   m.Organization.CreatedAt,
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func SwitchOrganization(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	orgID := c.Param("id")
+
+	var org models.Organization
+	if err := database.DB.First(&org, orgID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	if !isMember(principal.ID(), org.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if err := tx.Model(&models.User{ID: principal.ID()}).Update("organization_id", org.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch organization"})
+		return
+	}
+	if err := syncActiveMembership(tx, principal.ID()); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch organization"})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Active organization switched", "organization_id": org.ID})
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
 	"net/http"
 	"os"
@@ -24,19 +26,13 @@ func GetTags(c *gin.Context) {
 }
 
 func GetNewsFeed(c *gin.Context) {
-	userID, _ := c.Get("userID")
+	principal := middleware.GetPrincipal(c)
 	searchQuery := c.Query("search")
 	tagIDsParam := c.Query("tag_ids")
 	authorIDsParam := c.Query("author_ids")
 	teamIDsParam := c.Query("team_ids")
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusOK, []models.News{})
 		return
 	}
@@ -44,13 +40,13 @@ func GetNewsFeed(c *gin.Context) {
 	var news []models.News
 
 	db := database.DB.Model(&models.News{}).
-		Where("organization_id = ?", *user.OrganizationID).
+		Where("organization_id = ?", *principal.OrganizationID).
 		Preload("Author").
 		Preload("Tags")
 
-	if user.Role < models.RoleAdmin {
-		if user.TeamID != nil {
-			db = db.Where("news.team_id IS NULL OR news.team_id = ?", *user.TeamID)
+	if !principal.Can(models.PermNewsViewAll) {
+		if len(principal.VisibleTeamIDs) > 0 {
+			db = db.Where("news.team_id IS NULL OR news.team_id IN ?", principal.VisibleTeamIDs)
 		} else {
 			db = db.Where("news.team_id IS NULL")
 		}
@@ -90,8 +86,8 @@ func GetNewsFeed(c *gin.Context) {
 }
 
 func CreateNews(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
+	user := principal.User
 
 	title := c.PostForm("title")
 	content := c.PostForm("content")
@@ -118,9 +114,8 @@ func CreateNews(c *gin.Context) {
 		imagePath = "http://localhost:8080/" + dst
 	}
 
-	var user models.User
-	if err := database.DB.Preload("Team").First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in an organization"})
 		return
 	}
 
@@ -143,21 +138,21 @@ func CreateNews(c *gin.Context) {
 		Content:        content,
 		ImageURL:       imagePath,
 		Tags:           tags,
-		OrganizationID: *user.OrganizationID,
-		AuthorID:       userID,
+		OrganizationID: *principal.OrganizationID,
+		AuthorID:       principal.ID(),
 		CreatedAt:      time.Now(),
 	}
 
 	forTeam, _ := strconv.ParseBool(forTeamStr)
 
 	if forTeam {
-		if role >= 3 && targetTeamIDStr != "" {
+		if principal.Can(models.PermNewsPublishAnyTeam) && targetTeamIDStr != "" {
 			tID, err := strconv.ParseUint(targetTeamIDStr, 10, 32)
 			if err == nil {
 				teamID := uint(tID)
 
 				var checkTeam models.Team
-				err := database.DB.Where("id = ? AND organization_id = ?", teamID, *user.OrganizationID).First(&checkTeam).Error
+				err := database.DB.Where("id = ? AND organization_id = ?", teamID, *principal.OrganizationID).First(&checkTeam).Error
 				if err != nil {
 					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target team for your organization"})
 					return
@@ -165,20 +160,25 @@ func CreateNews(c *gin.Context) {
 				news.TeamID = &teamID
 			}
 		} else {
-			if user.TeamID == nil {
+			teamID := principal.TeamID
+			if tID, err := strconv.ParseUint(targetTeamIDStr, 10, 32); err == nil && principal.InTeam(uint(tID)) {
+				val := uint(tID)
+				teamID = &val
+			}
+			if teamID == nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in a team to post team news (or select one as admin)"})
 				return
 			}
 
-			isTeamLeader := user.Team != nil && user.Team.LeaderID != nil && *user.Team.LeaderID == user.ID
-			if role < models.RoleAdmin && !isTeamLeader {
+			isTeamLeader := principal.ManagesTeam(*teamID)
+			if !principal.Can(models.PermNewsPublishAnyTeam) && !isTeamLeader {
 				c.JSON(http.StatusForbidden, gin.H{"error": "Only team leaders can post team news"})
 				return
 			}
-			news.TeamID = user.TeamID
+			news.TeamID = teamID
 		}
 	} else {
-		if role < models.RoleAdmin {
+		if !principal.Can(models.PermNewsPublishGlobal) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can post global news"})
 			return
 		}
@@ -191,19 +191,16 @@ func CreateNews(c *gin.Context) {
 	}
 
 	news.Author = user
+	notifyNewsPosted(principal, &news)
+	events.Publish(newsEvent(events.NewsCreated, &news, news))
 	c.JSON(http.StatusCreated, news)
 }
 
 func GetOrganizationAuthors(c *gin.Context) {
-	userID, _ := c.Get("userID")
-	var currentUser models.User
-	if err := database.DB.First(&currentUser, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
 	var authors []models.User
-	err := database.DB.Where("organization_id = ? AND role > ?", currentUser.OrganizationID, 1).
+	err := database.DB.Where("id IN (SELECT user_id FROM memberships WHERE organization_id = ? AND role > ?)", principal.OrganizationID, 1).
 		Select("id, full_name").
 		Order("full_name asc").
 		Find(&authors).Error
@@ -217,8 +214,7 @@ func GetOrganizationAuthors(c *gin.Context) {
 
 func UpdateNews(c *gin.Context) {
 	newsID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var news models.News
 	if err := database.DB.Preload("Tags").First(&news, newsID).Error; err != nil {
@@ -226,7 +222,8 @@ func UpdateNews(c *gin.Context) {
 		return
 	}
 
-	if news.AuthorID != userID && role < models.RoleAdmin {
+	canModerate := principal.InOrganization(news.OrganizationID) && principal.Can(models.PermNewsModerate)
+	if news.AuthorID != principal.ID() && !canModerate {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
 		return
 	}
@@ -275,14 +272,14 @@ func UpdateNews(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update"})
 		return
 	}
+	events.Publish(newsEvent(events.NewsUpdated, &news, news))
 
 	c.JSON(http.StatusOK, news)
 }
 
 func DeleteNews(c *gin.Context) {
 	newsID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var news models.News
 	if err := database.DB.First(&news, newsID).Error; err != nil {
@@ -290,7 +287,8 @@ func DeleteNews(c *gin.Context) {
 		return
 	}
 
-	if news.AuthorID != userID && role < models.RoleAdmin {
+	canModerate := principal.InOrganization(news.OrganizationID) && principal.Can(models.PermNewsModerate)
+	if news.AuthorID != principal.ID() && !canModerate {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
 		return
 	}
@@ -303,6 +301,9 @@ func DeleteNews(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete from DB"})
 		return
 	}
+	recordAudit(c, database.DB, news.OrganizationID, models.AuditNewsDeleted, "news", news.ID,
+		gin.H{"title": news.Title, "author_id": news.AuthorID, "team_id": news.TeamID}, nil)
+	events.Publish(newsEvent(events.NewsDeleted, &news, gin.H{"id": news.ID, "team_id": news.TeamID}))
 
 	c.JSON(http.StatusOK, gin.H{"message": "News and image deleted"})
 }
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/notify"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// teamAudience returns the members of the team and of all its sub-teams,
+// who see the team's news.
+func teamAudience(db *gorm.DB, team *models.Team) []uint {
+	teamIDs := []uint{team.ID}
+	if tree, err := loadTeamTree(db, team.OrganizationID); err == nil {
+		teamIDs = append(teamIDs, tree.Descendants(team.ID)...)
+	}
+	var userIDs []uint
+	db.Model(&models.TeamMember{}).Where("team_id IN ?", teamIDs).Distinct().Pluck("user_id", &userIDs)
+	return userIDs
+}
+
+func notifyTaskAssigned(principal *middleware.Principal, task *models.Task) {
+	if task.AssigneeID == nil {
+		return
+	}
+	notify.Publish(database.DB, notify.Event{
+		Type:           models.NotifyTaskAssigned,
+		OrganizationID: &task.OrganizationID,
+		ActorID:        principal.ID(),
+		Recipients:     []uint{*task.AssigneeID},
+		Title:          "You were assigned a task",
+		Body:           principal.User.FullName + " assigned you \"" + task.Title + "\"",
+		EntityType:     "task",
+		EntityID:       task.ID,
+	})
+}
+
+func notifyTeamMembership(principal *middleware.Principal, team *models.Team, userID uint, role models.TeamRole) {
+	event := notify.Event{
+		Type:           models.NotifyTeamAdded,
+		OrganizationID: &team.OrganizationID,
+		ActorID:        principal.ID(),
+		Recipients:     []uint{userID},
+		Title:          "You were added to a team",
+		Body:           principal.User.FullName + " added you to " + team.Name,
+		EntityType:     "team",
+		EntityID:       team.ID,
+	}
+	if role == models.TeamRoleLeader {
+		event.Type = models.NotifyTeamLeader
+		event.Title = "You are now a team leader"
+		event.Body = principal.User.FullName + " made you the leader of " + team.Name
+	}
+	notify.Publish(database.DB, event)
+}
+
+func notifyNewsPosted(principal *middleware.Principal, news *models.News) {
+	if news.TeamID == nil {
+		return
+	}
+	var team models.Team
+	if err := database.DB.First(&team, *news.TeamID).Error; err != nil {
+		return
+	}
+	notify.Publish(database.DB, notify.Event{
+		Type:           models.NotifyNewsPosted,
+		OrganizationID: &news.OrganizationID,
+		ActorID:        principal.ID(),
+		Recipients:     teamAudience(database.DB, &team),
+		Title:          "New post in " + team.Name,
+		Body:           news.Title,
+		EntityType:     "news",
+		EntityID:       news.ID,
+	})
+}
+
+// GetNotifications lists the caller's notifications, newest first.
+// unread=true hides read ones; before=<id> and limit page through older ones.
+func GetNotifications(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	db := database.DB.Preload("Actor").Where("user_id = ?", principal.ID())
+	if c.Query("unread") == "true" {
+		db = db.Where("read_at IS NULL")
+	}
+	if before, err := strconv.ParseUint(c.Query("before"), 10, 64); err == nil {
+		db = db.Where("id < ?", before)
+	}
+	limit := 50
+	if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 && value <= 200 {
+		limit = value
+	}
+
+	var notifications []models.Notification
+	if err := db.Order("id desc").Limit(limit).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, notifications)
+}
+
+func GetUnreadNotificationCount(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var count int64
+	database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", principal.ID()).Count(&count)
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var notification models.Notification
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), principal.ID()).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+func MarkAllNotificationsRead(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", principal.ID()).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read", "updated": result.RowsAffected})
+}
+
+// GetNotificationPreferences returns every notification type with whether the caller receives it.
+func GetNotificationPreferences(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var stored []models.NotificationPreference
+	database.DB.Where("user_id = ?", principal.ID()).Find(&stored)
+	enabled := make(map[models.NotificationType]bool, len(stored))
+	for _, pref := range stored {
+		enabled[pref.Type] = pref.Enabled
+	}
+
+	prefs := make([]models.NotificationPreference, len(models.AllNotificationTypes))
+	for i, t := range models.AllNotificationTypes {
+		value, ok := enabled[t]
+		prefs[i] = models.NotificationPreference{Type: t, Enabled: !ok || value}
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences takes a map of notification type to enabled.
+func UpdateNotificationPreferences(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input map[models.NotificationType]bool
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	known := make(map[models.NotificationType]bool, len(models.AllNotificationTypes))
+	for _, t := range models.AllNotificationTypes {
+		known[t] = true
+	}
+	for t := range input {
+		if !known[t] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type: " + string(t)})
+			return
+		}
+	}
+
+	for t, enabled := range input {
+		pref := models.NotificationPreference{UserID: principal.ID(), Type: t, Enabled: enabled}
+		if err := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+		}).Create(&pref).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+			return
+		}
+	}
+
+	GetNotificationPreferences(c)
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +26,7 @@ type CreateTeamInput struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	LeaderID    uint   `json:"leader_id"`
+	ParentID    uint   `json:"parent_id"`
 }
 
 func CreateOrganization(c *gin.Context) {
@@ -44,34 +49,38 @@ func CreateOrganization(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create organization"})
 		return
 	}
-	result := tx.Model(&models.User{ID: userID}).Updates(map[string]interface{}{
-		"organization_id": org.ID,
-		"role":            int(models.RoleSuperAdmin),
-	})
-
-	if result.Error != nil {
+	membership := models.Membership{
+		UserID:         userID,
+		OrganizationID: org.ID,
+		Role:           models.RoleSuperAdmin,
+	}
+	if err := tx.Create(&membership).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user role"})
+		return
+	}
+	if err := tx.Model(&models.User{ID: userID}).Update("organization_id", org.ID).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user role"})
 		return
 	}
-	if result.RowsAffected == 0 {
+	if err := syncActiveMembership(tx, userID); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	if err := ensureSystemRoles(tx, org.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create organization roles"})
+		return
+	}
 
 	tx.Commit()
 	c.JSON(http.StatusCreated, org)
 }
 
 func CreateTeam(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-	userRole := c.MustGet("role").(models.Role)
-
-	if userRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Not enough permissions"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
 	var input CreateTeamInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -79,17 +88,14 @@ func CreateTeam(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	database.DB.Select("organization_id").First(&user, userID)
-
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You are not in an organization"})
 		return
 	}
 
 	var existingCount int64
 	database.DB.Model(&models.Team{}).
-		Where("organization_id = ? AND LOWER(name) = LOWER(?)", *user.OrganizationID, strings.TrimSpace(input.Name)).
+		Where("organization_id = ? AND LOWER(name) = LOWER(?)", *principal.OrganizationID, strings.TrimSpace(input.Name)).
 		Count(&existingCount)
 
 	if existingCount > 0 {
@@ -102,68 +108,82 @@ func CreateTeam(c *gin.Context) {
 	team := models.Team{
 		Name:           strings.TrimSpace(input.Name),
 		Description:    input.Description,
-		OrganizationID: *user.OrganizationID,
+		OrganizationID: *principal.OrganizationID,
 	}
 
+	var leaderMembership models.Membership
 	if input.LeaderID != 0 {
-		var leader models.User
-		if err := database.DB.First(&leader, input.LeaderID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Leader not found"})
-			return
-		}
-		if leader.OrganizationID == nil || *leader.OrganizationID != *user.OrganizationID {
+		var err error
+		leaderMembership, err = findMembership(database.DB, input.LeaderID, *principal.OrganizationID)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Leader must be from the same organization"})
 			return
 		}
 
 		team.LeaderID = &input.LeaderID
+	}
 
-		if leader.Role < models.RoleManager {
-			database.DB.Model(&leader).Update("role", models.RoleManager)
+	if input.ParentID != 0 {
+		var parent models.Team
+		if err := database.DB.Where("id = ? AND organization_id = ?", input.ParentID, *principal.OrganizationID).
+			First(&parent).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent team must be from the same organization"})
+			return
 		}
+		team.ParentID = &parent.ID
 	}
 
 	if err := database.DB.Create(&team).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
 		return
 	}
+	if team.LeaderID != nil {
+		if leaderMembership.Role < models.RoleManager {
+			updateMembership(database.DB, &leaderMembership, map[string]interface{}{"role": models.RoleManager})
+		}
+		setTeamMember(database.DB, &team, *team.LeaderID, models.TeamRoleLeader)
+	}
+	if team.ParentID != nil {
+		middleware.BumpOrganizationPrincipals(database.DB, team.OrganizationID)
+	}
 
 	c.JSON(http.StatusCreated, team)
 }
 
 func GetMyOrganization(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-
-	var user models.User
-	database.DB.First(&user, userID)
+	principal := middleware.GetPrincipal(c)
 
-	if user.OrganizationID == nil {
+	if principal.OrganizationID == nil {
 		c.JSON(http.StatusNotFound, gin.H{"message": "No organization"})
 		return
 	}
 
 	var org models.Organization
-	if err := database.DB.Preload("Teams").Preload("Users").First(&org, *user.OrganizationID).Error; err != nil {
+	if err := database.DB.Preload("Teams").First(&org, *principal.OrganizationID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
 		return
 	}
 
+	users, err := organizationMembers(database.DB, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	org.Users = users
+
 	c.JSON(http.StatusOK, org)
 }
 
 func GetTeamByID(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
+	principal := middleware.GetPrincipal(c)
 	teamID := c.Param("id")
 
-	var user models.User
-	database.DB.First(&user, requestorID)
-
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
 		return
 	}
-	if user.OrganizationID == nil || team.OrganizationID != *user.OrganizationID {
+	if !principal.InOrganization(team.OrganizationID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -171,6 +191,10 @@ func GetTeamByID(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func canManageTeam(principal *middleware.Principal, team *models.Team, perm models.Permission) bool {
+	return principal.InOrganization(team.OrganizationID) && principal.Can(perm)
+}
+
 func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 	response := models.TeamProfileResponse{
 		ID:             team.ID,
@@ -178,9 +202,25 @@ func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 		Description:    team.Description,
 		AvatarURL:      team.AvatarURL,
 		OrganizationID: team.OrganizationID,
+		ParentID:       team.ParentID,
 		LeaderID:       team.LeaderID,
 		CreatedAt:      team.CreatedAt,
 	}
+	if team.ParentID != nil {
+		var parent models.Team
+		if err := database.DB.First(&parent, *team.ParentID).Error; err == nil {
+			response.Parent = &models.TeamResponse{
+				ID:             parent.ID,
+				Name:           parent.Name,
+				Description:    parent.Description,
+				AvatarURL:      parent.AvatarURL,
+				OrganizationID: parent.OrganizationID,
+				ParentID:       parent.ParentID,
+				LeaderID:       parent.LeaderID,
+				CreatedAt:      parent.CreatedAt,
+			}
+		}
+	}
 	var org models.Organization
 	if err := database.DB.
 		Select("id", "name", "description", "avatar_url", "owner_id", "created_at").
@@ -199,6 +239,9 @@ func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 		if err := database.DB.
 			Select("id", "full_name", "email", "avatar_url", "role").
 			First(&leader, *team.LeaderID).Error; err == nil {
+			if m, err := findMembership(database.DB, leader.ID, team.OrganizationID); err == nil {
+				leader.Role = m.Role
+			}
 			response.Leader = &models.UserSimpleResponse{
 				ID:        leader.ID,
 				FullName:  leader.FullName,
@@ -208,11 +251,15 @@ func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 			}
 		}
 	}
-	var members []models.User
-	if err := database.DB.
-		Select("id", "full_name", "email", "avatar_url", "role", "team_id").
-		Where("team_id = ?", team.ID).
-		Find(&members).Error; err == nil {
+	var teamMembers []models.TeamMember
+	database.DB.Where("team_id = ?", team.ID).Find(&teamMembers)
+	teamRoles := make(map[uint]models.TeamRole, len(teamMembers))
+	for _, tm := range teamMembers {
+		teamRoles[tm.UserID] = tm.Role
+	}
+	if members, err := organizationMembers(database.DB, team.OrganizationID, func(db *gorm.DB) *gorm.DB {
+		return db.Where("memberships.user_id IN (SELECT user_id FROM team_members WHERE team_id = ?)", team.ID)
+	}); err == nil {
 		response.Members = make([]models.UserSimpleResponse, len(members))
 		for i, member := range members {
 			response.Members[i] = models.UserSimpleResponse{
@@ -221,6 +268,7 @@ func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 				Email:     member.Email,
 				AvatarURL: member.AvatarURL,
 				Role:      member.Role,
+				TeamRole:  teamRoles[member.ID],
 			}
 		}
 	}
@@ -229,22 +277,25 @@ func buildTeamProfileResponse(team *models.Team) models.TeamProfileResponse {
 }
 
 func UpdateTeam(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	teamID := c.Param("id")
 
 	var input struct {
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		ParentID    *uint  `json:"parent_id"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		return
 	}
 
 	var team models.Team
-	database.DB.First(&team, teamID)
-	isLeader := team.LeaderID != nil && *team.LeaderID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	if err := database.DB.First(&team, teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	isLeader := principal.LeadsTeam(team.ID)
+	isAdmin := canManageTeam(principal, &team, models.PermTeamUpdate)
 
 	if !isLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only Team Leader or Admin can edit team"})
@@ -267,50 +318,104 @@ func UpdateTeam(c *gin.Context) {
 		team.Name = newName
 	}
 
-	database.DB.Model(&team).Updates(models.Team{
-		Name:        input.Name,
-		Description: input.Description,
+	if input.ParentID != nil && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admin can move teams"})
+		return
+	}
+
+	var invalidParent error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if input.ParentID != nil {
+			newParentID, err := validateTeamParent(tx, &team, *input.ParentID)
+			if err != nil {
+				invalidParent = err
+				return err
+			}
+			if !sameTeamRef(team.ParentID, newParentID) {
+				team.ParentID = newParentID
+				if err := tx.Model(&team).Update("parent_id", team.ParentID).Error; err != nil {
+					return err
+				}
+				if err := middleware.BumpOrganizationPrincipals(tx, team.OrganizationID); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&team).Updates(models.Team{
+			Name:        input.Name,
+			Description: input.Description,
+		}).Error
 	})
+	if invalidParent != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidParent.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
 	c.JSON(http.StatusOK, team)
 }
 
 func UpdateOrganization(c *gin.Context) {
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	orgID := c.Param("id")
 
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins can edit organization"})
+	var input struct {
+		Name             string `json:"name"`
+		Description      string `json:"description"`
+		RequireTwoFactor *bool  `json:"require_two_factor"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var input struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
+	var org models.Organization
+	if err := database.DB.First(&org, orgID).Error; err != nil || !principal.InOrganization(org.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
 	}
-	c.ShouldBindJSON(&input)
 
-	var org models.Organization
-	database.DB.First(&org, orgID)
+	toggleTwoFactor := input.RequireTwoFactor != nil && *input.RequireTwoFactor != org.RequireTwoFactor
+	if toggleTwoFactor && *input.RequireTwoFactor && principal.User.TOTPEnabledAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Enable two-factor authentication for your own account first"})
+		return
+	}
 
-	database.DB.Model(&org).Updates(models.Organization{
-		Name:        input.Name,
-		Description: input.Description,
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&org).Updates(models.Organization{
+			Name:        input.Name,
+			Description: input.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if !toggleTwoFactor {
+			return nil
+		}
+		recordAudit(c, tx, org.ID, models.AuditOrgTwoFactor, "organization", org.ID,
+			gin.H{"require_two_factor": org.RequireTwoFactor}, gin.H{"require_two_factor": *input.RequireTwoFactor})
+		if err := tx.Model(&org).Update("require_two_factor", *input.RequireTwoFactor).Error; err != nil {
+			return err
+		}
+		return middleware.BumpOrganizationPrincipals(tx, org.ID)
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
 	c.JSON(http.StatusOK, org)
 }
 func GetOrganizationByID(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
+	principal := middleware.GetPrincipal(c)
 	targetOrgID := c.Param("id")
 
-	var user models.User
-	database.DB.First(&user, requestorID)
-
 	var org models.Organization
 	if err := database.DB.First(&org, targetOrgID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Org not found"})
 		return
 	}
-	if user.OrganizationID == nil || *user.OrganizationID != org.ID {
+	if !isMember(principal.ID(), org.ID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -330,7 +435,7 @@ func buildOrganizationProfileResponse(org *models.Organization) models.Organizat
 	}
 	var teams []models.Team
 	if err := database.DB.
-		Select("id", "name", "description", "avatar_url", "organization_id", "leader_id", "created_at").
+		Select("id", "name", "description", "avatar_url", "organization_id", "parent_id", "leader_id", "created_at").
 		Where("organization_id = ?", org.ID).
 		Find(&teams).Error; err == nil {
 
@@ -342,6 +447,7 @@ func buildOrganizationProfileResponse(org *models.Organization) models.Organizat
 				Description:    team.Description,
 				AvatarURL:      team.AvatarURL,
 				OrganizationID: team.OrganizationID,
+				ParentID:       team.ParentID,
 				LeaderID:       team.LeaderID,
 				CreatedAt:      team.CreatedAt,
 			}
@@ -350,6 +456,9 @@ func buildOrganizationProfileResponse(org *models.Organization) models.Organizat
 				if err := database.DB.
 					Select("id", "full_name", "email", "avatar_url", "role").
 					First(&leader, *team.LeaderID).Error; err == nil {
+					if m, err := findMembership(database.DB, leader.ID, team.OrganizationID); err == nil {
+						leader.Role = m.Role
+					}
 					teamResponse.Leader = &models.UserSimpleResponse{
 						ID:        leader.ID,
 						FullName:  leader.FullName,
@@ -362,11 +471,7 @@ func buildOrganizationProfileResponse(org *models.Organization) models.Organizat
 			response.Teams[i] = teamResponse
 		}
 	}
-	var users []models.User
-	if err := database.DB.
-		Select("id", "full_name", "email", "avatar_url", "role", "team_id", "created_at").
-		Where("organization_id = ?", org.ID).
-		Find(&users).Error; err == nil {
+	if users, err := organizationMembers(database.DB, org.ID); err == nil {
 
 		response.Users = make([]models.UserSimpleResponse, len(users))
 		for i, user := range users {
@@ -385,8 +490,7 @@ func buildOrganizationProfileResponse(org *models.Organization) models.Organizat
 
 func UploadTeamAvatar(c *gin.Context) {
 	teamID := c.Param("id")
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
@@ -394,8 +498,8 @@ func UploadTeamAvatar(c *gin.Context) {
 		return
 	}
 
-	isLeader := team.LeaderID != nil && *team.LeaderID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isLeader := principal.LeadsTeam(team.ID)
+	isAdmin := canManageTeam(principal, &team, models.PermTeamUpdate)
 
 	if !isLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -434,8 +538,7 @@ func UploadTeamAvatar(c *gin.Context) {
 
 func RemoveTeamAvatar(c *gin.Context) {
 	teamID := c.Param("id")
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
@@ -443,8 +546,8 @@ func RemoveTeamAvatar(c *gin.Context) {
 		return
 	}
 
-	isLeader := team.LeaderID != nil && *team.LeaderID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isLeader := principal.LeadsTeam(team.ID)
+	isAdmin := canManageTeam(principal, &team, models.PermTeamUpdate)
 
 	if !isLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -460,8 +563,7 @@ func RemoveTeamAvatar(c *gin.Context) {
 
 func UploadOrganizationAvatar(c *gin.Context) {
 	orgID := c.Param("id")
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var org models.Organization
 	if err := database.DB.First(&org, orgID).Error; err != nil {
@@ -469,8 +571,8 @@ func UploadOrganizationAvatar(c *gin.Context) {
 		return
 	}
 
-	isOwner := org.OwnerID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isOwner := org.OwnerID == principal.ID()
+	isAdmin := principal.InOrganization(org.ID) && principal.Can(models.PermOrgUpdate)
 
 	if !isOwner && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -511,8 +613,7 @@ func UploadOrganizationAvatar(c *gin.Context) {
 
 func RemoveOrganizationAvatar(c *gin.Context) {
 	orgID := c.Param("id")
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var org models.Organization
 	if err := database.DB.First(&org, orgID).Error; err != nil {
@@ -520,8 +621,8 @@ func RemoveOrganizationAvatar(c *gin.Context) {
 		return
 	}
 
-	isOwner := org.OwnerID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isOwner := org.OwnerID == principal.ID()
+	isAdmin := principal.InOrganization(org.ID) && principal.Can(models.PermOrgUpdate)
 
 	if !isOwner && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -537,13 +638,22 @@ func RemoveOrganizationAvatar(c *gin.Context) {
 }
 
 func GetFreeUsersInOrganization(c *gin.Context) {
-	orgID := c.Param("id")
+	principal := middleware.GetPrincipal(c)
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || !principal.InOrganization(uint(orgID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
 
-	var freeUsers []models.User
-	if err := database.DB.
-		Select("id", "full_name", "email", "avatar_url", "role").
-		Where("organization_id = ? AND (team_id IS NULL OR team_id = 0)", orgID).
-		Find(&freeUsers).Error; err != nil {
+	// With team_id, "free" means not in that team; otherwise not in any team of the organization.
+	teamID := c.Query("team_id")
+	freeUsers, err := organizationMembers(database.DB, uint(orgID), func(db *gorm.DB) *gorm.DB {
+		if teamID != "" {
+			return db.Where("memberships.user_id NOT IN (SELECT user_id FROM team_members WHERE team_id = ?)", teamID)
+		}
+		return db.Where("memberships.user_id NOT IN (SELECT tm.user_id FROM team_members tm JOIN teams t ON t.id = tm.team_id WHERE t.organization_id = ?)", orgID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
@@ -564,16 +674,23 @@ func GetFreeUsersInOrganization(c *gin.Context) {
 
 func AddTeamMember(c *gin.Context) {
 	teamID := c.Param("id")
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var input struct {
-		UserID uint `json:"user_id" binding:"required"`
+		UserID uint            `json:"user_id" binding:"required"`
+		Role   models.TeamRole `json:"role"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.Role == "" {
+		input.Role = models.TeamRoleMember
+	}
+	if input.Role != models.TeamRoleMember && input.Role != models.TeamRoleDeputy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Allowed: member, deputy"})
+		return
+	}
 
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
@@ -581,33 +698,31 @@ func AddTeamMember(c *gin.Context) {
 		return
 	}
 
-	isLeader := team.LeaderID != nil && *team.LeaderID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isLeader := principal.ManagesTeam(team.ID)
+	isAdmin := canManageTeam(principal, &team, models.PermTeamManageMembers)
 
 	if !isLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only Leader or Admin can add members"})
 		return
 	}
 
-	var targetUser models.User
-	if err := database.DB.First(&targetUser, input.UserID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	if targetUser.OrganizationID == nil || *targetUser.OrganizationID != team.OrganizationID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User belongs to another organization"})
+	if !isMember(input.UserID, team.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not a member of this organization"})
 		return
 	}
-	if targetUser.TeamID != nil && *targetUser.TeamID != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User is already in a team"})
+	var existingCount int64
+	database.DB.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", team.ID, input.UserID).Count(&existingCount)
+	if existingCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is already in this team"})
 		return
 	}
 
-	if err := database.DB.Model(&targetUser).Update("team_id", team.ID).Error; err != nil {
+	if err := setTeamMember(database.DB, &team, input.UserID, input.Role); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add user"})
 		return
 	}
+	notifyTeamMembership(principal, &team, input.UserID, input.Role)
+	publishMemberEvent(events.TeamMemberAdded, team.OrganizationID, input.UserID, &team.ID, input.Role)
 
 	c.JSON(http.StatusOK, gin.H{"message": "User added to team"})
 }
@@ -616,8 +731,7 @@ func RemoveTeamMember(c *gin.Context) {
 	teamID := c.Param("id")
 	targetUserID := c.Param("userId")
 
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var team models.Team
 	if err := database.DB.First(&team, teamID).Error; err != nil {
@@ -625,65 +739,83 @@ func RemoveTeamMember(c *gin.Context) {
 		return
 	}
 
-	isLeader := team.LeaderID != nil && *team.LeaderID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isLeader := principal.ManagesTeam(team.ID)
+	isAdmin := canManageTeam(principal, &team, models.PermTeamManageMembers)
 
 	if !isLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
 
-	var targetUser models.User
-	if err := database.DB.First(&targetUser, targetUserID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	if targetUser.TeamID == nil || *targetUser.TeamID != team.ID {
+	var teamMember models.TeamMember
+	if err := database.DB.Where("team_id = ? AND user_id = ?", team.ID, targetUserID).First(&teamMember).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not in this team"})
 		return
 	}
 
-	if team.LeaderID != nil && *team.LeaderID == targetUser.ID {
+	if teamMember.Role == models.TeamRoleLeader {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove team leader. Change leader first."})
 		return
 	}
 
-	if err := database.DB.Model(&targetUser).Update("team_id", gorm.Expr("NULL")).Error; err != nil {
+	if err := removeTeamMember(database.DB, &team, teamMember.UserID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user"})
 		return
 	}
+	publishMemberEvent(events.TeamMemberRemoved, team.OrganizationID, teamMember.UserID, &team.ID, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "User removed from team"})
 }
 
 func DeleteTeam(c *gin.Context) {
 	teamID := c.Param("id")
-	requestorRole := c.MustGet("role").(models.Role)
-
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
 	var team models.Team
-	if err := database.DB.First(&team, teamID).Error; err != nil {
+	if err := database.DB.First(&team, teamID).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
 		return
 	}
 
+	var affectedUserIDs []uint
+	database.DB.Model(&models.TeamMember{}).Where("team_id = ?", team.ID).Pluck("user_id", &affectedUserIDs)
+
 	tx := database.DB.Begin()
-	if err := tx.Model(&models.User{}).Where("team_id = ?", team.ID).Update("team_id", gorm.Expr("NULL")).Error; err != nil {
+	if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMember{}).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update members"})
 		return
 	}
+	if err := deleteTeamWorkflow(tx, team.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if err := tx.Where("team_id = ?", team.ID).Delete(&models.TaskRecurrence{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if err := tx.Model(&models.Task{}).Where("team_id = ?", team.ID).Update("sprint_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if err := tx.Where("team_id = ?", team.ID).Delete(&models.Sprint{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if err := tx.Model(&models.Team{}).Where("parent_id = ?", team.ID).Update("parent_id", team.ParentID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move sub-teams"})
+		return
+	}
 	if team.LeaderID != nil {
-		var leader models.User
-		if err := tx.First(&leader, *team.LeaderID).Error; err == nil {
-			if leader.Role == models.RoleManager {
-				tx.Model(&leader).Update("role", models.RoleUser)
-			}
+		if err := demoteFormerLeader(tx, *team.LeaderID, &team); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote team leader"})
+			return
 		}
 	}
 	if err := tx.Delete(&team).Error; err != nil {
@@ -691,23 +823,58 @@ func DeleteTeam(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
 		return
 	}
+	for _, userID := range affectedUserIDs {
+		if err := syncPrimaryTeam(tx, userID, team.OrganizationID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update members"})
+			return
+		}
+	}
+
+	if err := middleware.BumpOrganizationPrincipals(tx, team.OrganizationID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	recordAudit(c, tx, team.OrganizationID, models.AuditTeamDeleted, "team", team.ID,
+		gin.H{"name": team.Name, "parent_id": team.ParentID, "leader_id": team.LeaderID, "members": len(affectedUserIDs)}, nil)
 
 	tx.Commit()
 	c.JSON(http.StatusOK, gin.H{"message": "Team deleted"})
 }
 
+// demoteFormerLeader turns a Manager who stops leading the team back into an
+// Employee, unless they still lead another team.
+func demoteFormerLeader(tx *gorm.DB, userID uint, team *models.Team) error {
+	membership, err := findMembership(tx, userID, team.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if membership.Role != models.RoleManager {
+		return nil
+	}
+	var otherTeamsCount int64
+	if err := tx.Model(&models.Team{}).
+		Where("leader_id = ? AND id != ?", userID, team.ID).
+		Count(&otherTeamsCount).Error; err != nil {
+		return err
+	}
+	if otherTeamsCount > 0 {
+		return nil
+	}
+	return updateMembership(tx, &membership, map[string]interface{}{"role": models.RoleEmployee})
+}
+
 type UpdateLeaderInput struct {
 	LeaderID *uint `json:"leader_id"`
 }
 
 func UpdateTeamLeader(c *gin.Context) {
 	teamID := c.Param("id")
-	requestorRole := c.MustGet("role").(models.Role)
-
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-		return
-	}
+	principal := middleware.GetPrincipal(c)
 
 	var input UpdateLeaderInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -723,48 +890,53 @@ func UpdateTeamLeader(c *gin.Context) {
 	}()
 
 	var team models.Team
-	if err := tx.First(&team, teamID).Error; err != nil {
+	if err := tx.First(&team, teamID).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
 		tx.Rollback()
 		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
 		return
 	}
 
+	var affectedUserIDs []uint
+	previousLeaderID := team.LeaderID
 	if team.LeaderID != nil {
-		var oldLeader models.User
-		if err := tx.First(&oldLeader, *team.LeaderID).Error; err == nil {
-			if oldLeader.Role == models.RoleManager {
-				var otherTeamsCount int64
-				if err := tx.Model(&models.Team{}).
-					Where("leader_id = ? AND id != ?", oldLeader.ID, team.ID).
-					Count(&otherTeamsCount).Error; err == nil && otherTeamsCount == 0 {
-					if err := tx.Model(&oldLeader).Update("role", models.RoleEmployee).Error; err != nil {
-						tx.Rollback()
-						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote old leader"})
-						return
-					}
-				}
-			}
+		affectedUserIDs = append(affectedUserIDs, *team.LeaderID)
+	}
+	if input.LeaderID != nil && *input.LeaderID != 0 {
+		affectedUserIDs = append(affectedUserIDs, *input.LeaderID)
+	}
+
+	if team.LeaderID != nil {
+		if err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND user_id = ?", team.ID, *team.LeaderID).
+			Update("role", models.TeamRoleMember).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote old leader"})
+			return
+		}
+		if err := demoteFormerLeader(tx, *team.LeaderID, &team); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote old leader"})
+			return
 		}
 	}
 	if input.LeaderID != nil && *input.LeaderID != 0 {
-		var newLeader models.User
-		if err := tx.First(&newLeader, *input.LeaderID).Error; err != nil {
+		var user models.User
+		if err := tx.Select("id").First(&user, *input.LeaderID).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusNotFound, gin.H{"error": "New leader not found"})
 			return
 		}
 
-		if newLeader.OrganizationID == nil || *newLeader.OrganizationID != team.OrganizationID {
+		newLeader, err := findMembership(tx, user.ID, team.OrganizationID)
+		if err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{"error": "User belongs to another organization"})
 			return
 		}
-		if newLeader.TeamID == nil || *newLeader.TeamID != team.ID {
-			if err := tx.Model(&newLeader).Update("team_id", team.ID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add leader to team"})
-				return
-			}
+		if err := setTeamMember(tx, &team, newLeader.UserID, models.TeamRoleLeader); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add leader to team"})
+			return
 		}
 		if newLeader.Role < models.RoleManager {
 			if err := tx.Model(&newLeader).Update("role", models.RoleManager).Error; err != nil {
@@ -785,22 +957,41 @@ func UpdateTeamLeader(c *gin.Context) {
 		return
 	}
 
+	for _, userID := range affectedUserIDs {
+		if err := syncPrimaryTeam(tx, userID, team.OrganizationID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+			return
+		}
+	}
+	if !sameTeamRef(team.LeaderID, previousLeaderID) {
+		recordAudit(c, tx, team.OrganizationID, models.AuditTeamLeaderChanged, "team", team.ID,
+			gin.H{"leader_id": previousLeaderID}, gin.H{"leader_id": team.LeaderID})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
 		return
 	}
 
+	if team.LeaderID != nil && !sameTeamRef(team.LeaderID, previousLeaderID) {
+		notifyTeamMembership(principal, &team, *team.LeaderID, models.TeamRoleLeader)
+	}
+	if !sameTeamRef(team.LeaderID, previousLeaderID) {
+		for _, userID := range affectedUserIDs {
+			publishMemberEvent(events.TeamMemberUpdated, team.OrganizationID, userID, &team.ID, nil)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Leader updated successfully"})
 }
 
 func GetOrganizationTeams(c *gin.Context) {
-	userID, _ := c.Get("userID")
-	var user models.User
-	database.DB.First(&user, userID)
+	principal := middleware.GetPrincipal(c)
 
 	var teams []models.Team
-	err := database.DB.Where("organization_id = ?", user.OrganizationID).
+	err := database.DB.Where("organization_id = ?", principal.OrganizationID).
 		Order("name asc").
 		Find(&teams).Error
 
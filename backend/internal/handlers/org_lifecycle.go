@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TransferOwnershipInput struct {
+	NewOwnerID  uint   `json:"new_owner_id" binding:"required"`
+	ConfirmName string `json:"confirm_name" binding:"required"`
+}
+
+type DeleteOrganizationInput struct {
+	ConfirmName string `json:"confirm_name" binding:"required"`
+}
+
+// loadOwnedOrganization returns the organization from the :id param if the
+// principal owns it and confirmName repeats its name.
+func loadOwnedOrganization(c *gin.Context, confirmName string) (*models.Organization, bool) {
+	principal := middleware.GetPrincipal(c)
+
+	var org models.Organization
+	if err := database.DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+	if org.OwnerID != principal.ID() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can do this"})
+		return nil, false
+	}
+	if strings.TrimSpace(confirmName) != org.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Confirmation does not match organization name"})
+		return nil, false
+	}
+	return &org, true
+}
+
+func TransferOwnership(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input TransferOwnershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, ok := loadOwnedOrganization(c, input.ConfirmName)
+	if !ok {
+		return
+	}
+	if input.NewOwnerID == principal.ID() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this organization"})
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	oldOwner, err := findMembership(tx, principal.ID(), org.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Owner membership not found"})
+		return
+	}
+	newOwner, err := findMembership(tx, input.NewOwnerID, org.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New owner must be a member of the organization"})
+		return
+	}
+
+	if err := tx.Model(org).Update("owner_id", newOwner.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	if err := updateMembership(tx, &newOwner, map[string]interface{}{"role": models.RoleSuperAdmin, "org_role_id": nil}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	if err := updateMembership(tx, &oldOwner, map[string]interface{}{"role": models.RoleAdmin, "org_role_id": nil}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	recordAudit(c, tx, org.ID, models.AuditOrgTransferred, "organization", org.ID,
+		gin.H{"owner_id": oldOwner.UserID}, gin.H{"owner_id": newOwner.UserID})
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transferred", "owner_id": newOwner.UserID})
+}
+
+func DeleteOrganization(c *gin.Context) {
+	var input DeleteOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, ok := loadOwnedOrganization(c, input.ConfirmName)
+	if !ok {
+		return
+	}
+
+	var memberIDs []uint
+	database.DB.Model(&models.Membership{}).Where("organization_id = ?", org.ID).Pluck("user_id", &memberIDs)
+
+	var newsImages, documentFiles, teamAvatars, attachmentFiles []string
+	database.DB.Model(&models.News{}).Where("organization_id = ? AND image_url != ''", org.ID).Pluck("image_url", &newsImages)
+	database.DB.Model(&models.Document{}).Where("organization_id = ?", org.ID).Pluck("file_url", &documentFiles)
+	database.DB.Model(&models.Team{}).Where("organization_id = ? AND avatar_url != ''", org.ID).Pluck("avatar_url", &teamAvatars)
+	files := append([]string{org.AvatarURL}, newsImages...)
+	files = append(files, documentFiles...)
+	database.DB.Model(&models.TaskAttachment{}).
+		Where("task_id IN (SELECT id FROM tasks WHERE organization_id = ?)", org.ID).
+		Pluck("file_path", &attachmentFiles)
+	files = append(files, teamAvatars...)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		steps := []string{
+			"DELETE FROM news_tags WHERE news_id IN (SELECT id FROM news WHERE organization_id = ?)",
+			"DELETE FROM document_tags WHERE document_id IN (SELECT id FROM documents WHERE organization_id = ?)",
+			"DELETE FROM news WHERE organization_id = ?",
+			"DELETE FROM documents WHERE organization_id = ?",
+			"DELETE FROM task_comments WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM task_activities WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM task_checklist_items WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM task_time_logs WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM task_attachments WHERE task_id IN (SELECT id FROM tasks WHERE organization_id = ?)",
+			"DELETE FROM tasks WHERE organization_id = ?",
+			"DELETE FROM task_recurrences WHERE organization_id = ?",
+			"DELETE FROM sprints WHERE organization_id = ?",
+			"DELETE FROM invites WHERE organization_id = ?",
+			"DELETE FROM team_members WHERE team_id IN (SELECT id FROM teams WHERE organization_id = ?)",
+			"DELETE FROM team_workflow_statuses WHERE team_id IN (SELECT id FROM teams WHERE organization_id = ?)",
+			"DELETE FROM team_workflow_transitions WHERE team_id IN (SELECT id FROM teams WHERE organization_id = ?)",
+			"DELETE FROM teams WHERE organization_id = ?",
+			"DELETE FROM role_permissions WHERE org_role_id IN (SELECT id FROM org_roles WHERE organization_id = ?)",
+			"DELETE FROM org_roles WHERE organization_id = ?",
+			"DELETE FROM memberships WHERE organization_id = ?",
+			"DELETE FROM notifications WHERE organization_id = ?",
+			"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE organization_id = ?)",
+			"DELETE FROM webhook_subscriptions WHERE webhook_id IN (SELECT id FROM webhooks WHERE organization_id = ?)",
+			"DELETE FROM webhooks WHERE organization_id = ?",
+			"DELETE FROM audit_events WHERE organization_id = ?",
+		}
+		for _, query := range steps {
+			if err := tx.Exec(query, org.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(org).Error; err != nil {
+			return err
+		}
+		// Recorded after the organization's audit log is purged, so this one
+		// event is kept until the retention job removes it.
+		recordAudit(c, tx, org.ID, models.AuditOrgDeleted, "organization", org.ID,
+			gin.H{"name": org.Name, "members": memberIDs}, nil)
+		for _, userID := range memberIDs {
+			if err := syncActiveMembership(tx, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	for _, url := range files {
+		removeUploadedFile(url)
+	}
+	for _, path := range attachmentFiles {
+		removeAttachmentFile(path)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted"})
+}
+
+// removeUploadedFile deletes a file referenced either by an absolute
+// "http://localhost:8080/uploads/..." URL or a relative "/uploads/..." path.
+func removeUploadedFile(fileURL string) {
+	path := strings.TrimPrefix(fileURL, "http://localhost:8080")
+	if !strings.HasPrefix(path, "/uploads/") || strings.Contains(path, "..") {
+		return
+	}
+	_ = os.Remove(strings.TrimPrefix(path, "/"))
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/mailer"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var errInvalidUserToken = errors.New("Invalid or expired link")
+
+type ForgotPasswordInput struct {
+	Email  string `json:"email" binding:"required,email"`
+	Locale string `json:"locale"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// requestLocale prefers an explicit locale and falls back to Accept-Language.
+func requestLocale(c *gin.Context, locale string) string {
+	if locale != "" {
+		return locale
+	}
+	return c.GetHeader("Accept-Language")
+}
+
+// emailVerificationRequired reports whether unverified users are kept out of
+// organizations (REQUIRE_EMAIL_VERIFICATION=true).
+func emailVerificationRequired() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// issueUserToken replaces the user's unused tokens for the purpose with a new
+// one and returns the raw value for the link.
+func issueUserToken(db *gorm.DB, userID uint, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	raw, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := db.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Delete(&models.UserToken{}).Error; err != nil {
+		return "", err
+	}
+	token := models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// consumeUserToken marks the token used and returns it. A token can only be
+// consumed once, even by concurrent requests.
+func consumeUserToken(db *gorm.DB, raw string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	var token models.UserToken
+	if err := db.Where("token_hash = ? AND purpose = ?", utils.HashToken(raw), purpose).First(&token).Error; err != nil {
+		return nil, errInvalidUserToken
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+	result := db.Model(&models.UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvalidUserToken
+	}
+	return &token, nil
+}
+
+func sendVerificationEmail(user *models.User, locale string) error {
+	raw, err := issueUserToken(database.DB, user.ID, models.TokenEmailVerify, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return mailer.SendTemplate(user.Email, locale, "email_verification", gin.H{
+		"Name":           user.FullName,
+		"Email":          user.Email,
+		"Link":           mailer.Link("/verify-email?token=" + raw),
+		"ExpiresInHours": int(emailVerificationTTL.Hours()),
+	})
+}
+
+// ForgotPassword emails a reset link. The response is the same whether or
+// not the account exists.
+func ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err == nil {
+		raw, err := issueUserToken(database.DB, user.ID, models.TokenPasswordReset, passwordResetTTL)
+		if err == nil {
+			err = mailer.SendTemplate(user.Email, requestLocale(c, input.Locale), "password_reset", gin.H{
+				"Name":             user.FullName,
+				"Link":             mailer.Link("/reset-password?token=" + raw),
+				"ExpiresInMinutes": int(passwordResetTTL.Minutes()),
+			})
+		}
+		if err != nil {
+			log.Printf("Password reset for user %d failed: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password from a reset link and signs the user out
+// everywhere.
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, input.Token, models.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		// The link proves the user owns the address.
+		updates := map[string]interface{}{"password": string(hashedPassword)}
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return errInvalidUserToken
+		}
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+		return middleware.BumpPrincipalVersion(tx, user.ID)
+	})
+	if errors.Is(err, errInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// ChangePassword checks the current password and signs out every other session.
+func ChangePassword(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	sessionID := c.MustGet("sessionID").(uint)
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(principal.User.Password), []byte(input.OldPassword)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", principal.ID()).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND id != ? AND revoked_at IS NULL", principal.ID(), sessionID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return middleware.BumpPrincipalVersion(tx, principal.ID())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+func VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, input.Token, models.TokenEmailVerify)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return middleware.BumpPrincipalVersion(tx, token.UserID)
+	})
+	if errors.Is(err, errInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+func ResendVerificationEmail(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	user := principal.User
+
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+	if err := sendVerificationEmail(&user, requestLocale(c, c.Query("locale"))); err != nil {
+		log.Printf("Verification email for user %d failed: %v", user.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
 	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
 	"fmt"
@@ -24,66 +25,93 @@ type UpdateUserInput struct {
 }
 
 func DeleteAccount(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-
-	var user models.User
-	database.DB.First(&user, userID)
-
-	if user.Role == models.RoleSuperAdmin {
+	principal := middleware.GetPrincipal(c)
+	user := principal.User
+
+	var ownedCount int64
+	database.DB.Model(&models.Membership{}).
+		Where("user_id = ? AND role = ?", user.ID, models.RoleSuperAdmin).
+		Count(&ownedCount)
+	if ownedCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner cannot delete account. Delete organization first or transfer ownership."})
 		return
 	}
 
+	revokeUserSessions(database.DB, user.ID)
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.TeamMember{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.Membership{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.Notification{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.NotificationPreference{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.OverdueDigest{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.UserToken{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.ExternalIdentity{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+	database.DB.Where("api_token_id IN (?)", database.DB.Model(&models.APIToken{}).Select("id").Where("user_id = ?", user.ID)).Delete(&models.APITokenScope{})
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.APIToken{})
 	database.DB.Delete(&user)
 	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
 }
 
 func LeaveOrganization(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-
-	var user models.User
-	database.DB.First(&user, userID)
+	principal := middleware.GetPrincipal(c)
 
-	if user.Role == models.RoleSuperAdmin {
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are not in an organization"})
+		return
+	}
+	if principal.Role == models.RoleSuperAdmin {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner cannot leave organization."})
 		return
 	}
 
-	database.DB.Model(&user).Updates(map[string]interface{}{
-		"organization_id": nil,
-		"team_id":         nil,
-		"role":            models.RoleUser,
-	})
+	if err := removeMembership(database.DB, principal.ID(), *principal.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave organization"})
+		return
+	}
+	publishMemberEvent(events.MemberLeft, *principal.OrganizationID, principal.ID(), nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "You left the organization"})
 }
 
 func GetUserByID(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
+	principal := middleware.GetPrincipal(c)
 	targetID := c.Param("id")
 
-	var requestor models.User
-	database.DB.First(&requestor, requestorID)
-
 	var target models.User
 	if err := database.DB.First(&target, targetID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-	if requestor.ID != target.ID {
-		if requestor.OrganizationID == nil || target.OrganizationID == nil || *requestor.OrganizationID != *target.OrganizationID {
+	if principal.ID() != target.ID {
+		membership, ok := membershipInActiveOrganization(principal, target.ID)
+		if !ok {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You can only view profiles within your organization"})
 			return
 		}
+		target = applyMembership(target, membership)
 	}
 	response := buildUserProfileResponse(&target)
 	c.JSON(http.StatusOK, response)
 }
 
+// membershipInActiveOrganization returns the user's membership in the principal's active organization.
+func membershipInActiveOrganization(principal *middleware.Principal, userID uint) (models.Membership, bool) {
+	if principal.OrganizationID == nil {
+		return models.Membership{}, false
+	}
+	membership, err := findMembership(database.DB, userID, *principal.OrganizationID)
+	return membership, err == nil
+}
+
+func canManageUser(principal *middleware.Principal, target *models.User, perm models.Permission) bool {
+	return principal.OrganizationID != nil && principal.Can(perm) && isMember(target.ID, *principal.OrganizationID)
+}
+
 func buildUserProfileResponse(user *models.User) models.UserProfileResponse {
 	response := models.UserProfileResponse{
 		ID:             user.ID,
 		Email:          user.Email,
+		EmailVerified:  user.EmailVerifiedAt != nil,
 		FullName:       user.FullName,
 		AvatarURL:      user.AvatarURL,
 		Bio:            user.Bio,
@@ -95,6 +123,10 @@ func buildUserProfileResponse(user *models.User) models.UserProfileResponse {
 		UpdatedAt:      user.UpdatedAt,
 	}
 
+	if user.OrganizationID != nil {
+		response.Teams = userTeams(database.DB, user.ID, *user.OrganizationID)
+	}
+
 	if user.OrganizationID != nil {
 		var org models.Organization
 		if err := database.DB.
@@ -114,7 +146,7 @@ func buildUserProfileResponse(user *models.User) models.UserProfileResponse {
 	if user.TeamID != nil {
 		var team models.Team
 		if err := database.DB.
-			Select("id", "name", "description", "avatar_url", "organization_id", "leader_id", "created_at").
+			Select("id", "name", "description", "avatar_url", "organization_id", "parent_id", "leader_id", "created_at").
 			First(&team, *user.TeamID).Error; err == nil {
 
 			teamResponse := &models.TeamResponse{
@@ -123,6 +155,7 @@ func buildUserProfileResponse(user *models.User) models.UserProfileResponse {
 				Description:    team.Description,
 				AvatarURL:      team.AvatarURL,
 				OrganizationID: team.OrganizationID,
+				ParentID:       team.ParentID,
 				LeaderID:       team.LeaderID,
 				CreatedAt:      team.CreatedAt,
 			}
@@ -149,8 +182,7 @@ func buildUserProfileResponse(user *models.User) models.UserProfileResponse {
 }
 
 func UpdateUserByID(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	targetID := c.Param("id")
 
 	targetIDUint, err := strconv.ParseUint(targetID, 10, 32)
@@ -164,8 +196,8 @@ func UpdateUserByID(c *gin.Context) {
 		return
 	}
 
-	isSelf := target.ID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isSelf := target.ID == principal.ID()
+	isAdmin := canManageUser(principal, &target, models.PermUserEditAny)
 
 	if !isSelf && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -190,29 +222,36 @@ func UpdateUserByID(c *gin.Context) {
 		}
 	}
 
-	if isAdmin {
+	membership, inOrg := membershipInActiveOrganization(principal, target.ID)
+	membershipUpdates := make(map[string]interface{})
+	var joinTeam *models.Team
+	canManageTeams := inOrg && principal.Can(models.PermTeamManageMembers)
+	canManageRoles := inOrg && !isSelf && membership.Role != models.RoleSuperAdmin && principal.Can(models.PermMemberManageRoles)
+
+	if canManageTeams || canManageRoles {
 		if c.Request.Form != nil {
-			if _, hasTeamID := c.Request.Form["team_id"]; hasTeamID && teamIDStr != "" {
+			if _, hasTeamID := c.Request.Form["team_id"]; canManageTeams && hasTeamID && teamIDStr != "" {
 				teamIDUint, err := strconv.ParseUint(teamIDStr, 10, 32)
 				if err == nil {
 					teamID := uint(teamIDUint)
 					if teamID == 0 {
-						updates["team_id"] = nil
+						joinTeam = &models.Team{}
 					} else {
 						var team models.Team
 						if err := database.DB.First(&team, teamID).Error; err == nil {
-							if target.OrganizationID != nil && team.OrganizationID == *target.OrganizationID {
-								updates["team_id"] = teamID
+							if team.OrganizationID == membership.OrganizationID {
+								joinTeam = &team
 							}
 						}
 					}
 				}
 			}
 
-			if _, hasRole := c.Request.Form["role"]; hasRole && roleStr != "" {
+			if _, hasRole := c.Request.Form["role"]; canManageRoles && hasRole && roleStr != "" {
 				roleInt, err := strconv.Atoi(roleStr)
-				if err == nil && roleInt >= int(models.RoleUser) && roleInt <= int(models.RoleSuperAdmin) {
-					updates["role"] = roleInt
+				if err == nil && roleInt >= int(models.RoleUser) && roleInt < int(models.RoleSuperAdmin) && roleInt <= int(principal.Role) {
+					membershipUpdates["role"] = roleInt
+					membershipUpdates["org_role_id"] = nil
 				}
 			}
 		}
@@ -248,8 +287,42 @@ func UpdateUserByID(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user: " + err.Error()})
 			return
 		}
+		middleware.BumpPrincipalVersion(database.DB, target.ID)
+	}
+	if joinTeam != nil {
+		var err error
+		if joinTeam.ID == 0 {
+			// team_id=0 takes the user out of every team they do not lead.
+			err = database.DB.Where("user_id = ? AND role != ? AND team_id IN (SELECT id FROM teams WHERE organization_id = ?)",
+				target.ID, models.TeamRoleLeader, membership.OrganizationID).Delete(&models.TeamMember{}).Error
+			if err == nil {
+				err = syncPrimaryTeam(database.DB, target.ID, membership.OrganizationID)
+			}
+		} else {
+			err = addToTeamAsPrimary(database.DB, joinTeam, &membership)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user: " + err.Error()})
+			return
+		}
+		membership, _ = findMembership(database.DB, target.ID, membership.OrganizationID)
+	}
+	if len(membershipUpdates) > 0 {
+		if err := updateMembership(database.DB, &membership, membershipUpdates); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user: " + err.Error()})
+			return
+		}
+		if _, roleChanged := membershipUpdates["role"]; roleChanged {
+			revokeUserSessions(database.DB, target.ID)
+		}
+	}
+	if len(updates) > 0 || len(membershipUpdates) > 0 || joinTeam != nil {
 		database.DB.First(&target, target.ID)
 	}
+	if inOrg {
+		membership, _ = findMembership(database.DB, target.ID, membership.OrganizationID)
+		target = applyMembership(target, membership)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Profile updated successfully",
@@ -258,13 +331,8 @@ func UpdateUserByID(c *gin.Context) {
 }
 
 func UploadAvatar(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
+	user := middleware.GetPrincipal(c).User
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
 	config := middleware.DefaultUploadConfig("uploads/avatars")
 	middleware.Upload(config)(c)
 
@@ -289,6 +357,7 @@ func UploadAvatar(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update avatar"})
 		return
 	}
+	middleware.BumpPrincipalVersion(database.DB, user.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Avatar uploaded successfully",
@@ -297,8 +366,7 @@ func UploadAvatar(c *gin.Context) {
 }
 
 func UploadUserAvatar(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	targetID := c.Param("id")
 
 	targetIDUint, err := strconv.ParseUint(targetID, 10, 32)
@@ -313,8 +381,8 @@ func UploadUserAvatar(c *gin.Context) {
 		return
 	}
 
-	isSelf := target.ID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isSelf := target.ID == principal.ID()
+	isAdmin := canManageUser(principal, &target, models.PermUserEditAny)
 
 	if !isSelf && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -360,6 +428,7 @@ func UploadUserAvatar(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update avatar"})
 		return
 	}
+	middleware.BumpPrincipalVersion(database.DB, target.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Avatar uploaded successfully",
@@ -368,8 +437,7 @@ func UploadUserAvatar(c *gin.Context) {
 }
 
 func RemoveUserAvatar(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	targetID := c.Param("id")
 
 	targetIDUint, err := strconv.ParseUint(targetID, 10, 32)
@@ -384,8 +452,8 @@ func RemoveUserAvatar(c *gin.Context) {
 		return
 	}
 
-	isSelf := target.ID == requestorID
-	isAdmin := requestorRole >= models.RoleAdmin
+	isSelf := target.ID == principal.ID()
+	isAdmin := canManageUser(principal, &target, models.PermUserEditAny)
 
 	if !isSelf && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
@@ -407,6 +475,7 @@ func RemoveUserAvatar(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove avatar"})
 		return
 	}
+	middleware.BumpPrincipalVersion(database.DB, target.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Avatar removed successfully",
@@ -414,49 +483,39 @@ func RemoveUserAvatar(c *gin.Context) {
 }
 
 func KickFromOrganization(c *gin.Context) {
-	requestorID := c.MustGet("userID").(uint)
-	requestorRole := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 	targetID := c.Param("id")
 
-	if requestorRole < models.RoleAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can remove members"})
-		return
-	}
-
-	var requestor models.User
-	database.DB.First(&requestor, requestorID)
-
 	var target models.User
 	if err := database.DB.First(&target, targetID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	if requestor.ID == target.ID {
+	if principal.ID() == target.ID {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Use 'Leave' to exit organization yourself"})
 		return
 	}
 
-	if requestor.OrganizationID == nil || target.OrganizationID == nil || *requestor.OrganizationID != *target.OrganizationID {
+	membership, ok := membershipInActiveOrganization(principal, target.ID)
+	if !ok {
 		c.JSON(http.StatusForbidden, gin.H{"error": "User is not in your organization"})
 		return
 	}
 
-	if target.Role == models.RoleSuperAdmin {
+	if membership.Role == models.RoleSuperAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot remove the organization owner"})
 		return
 	}
 
-	err := database.DB.Model(&target).Updates(map[string]interface{}{
-		"organization_id": nil,
-		"team_id":         nil,
-		"role":            models.RoleUser,
-	}).Error
-
-	if err != nil {
+	if err := removeMembership(database.DB, target.ID, membership.OrganizationID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user"})
 		return
 	}
+	revokeUserSessions(database.DB, target.ID)
+	recordAudit(c, database.DB, membership.OrganizationID, models.AuditMemberKicked, "user", target.ID,
+		gin.H{"email": target.Email, "role": membership.Role, "team_id": membership.TeamID}, nil)
+	publishMemberEvent(events.MemberRemoved, membership.OrganizationID, target.ID, nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User %s removed from organization", target.FullName)})
 }
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleInput struct {
+	Name        string              `json:"name"`
+	BaseRole    *models.Role        `json:"base_role"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+func ensureSystemRoles(db *gorm.DB, orgID uint) error {
+	var count int64
+	if err := db.Model(&models.OrgRole{}).Where("organization_id = ? AND is_system = ?", orgID, true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, base := range []models.Role{models.RoleUser, models.RoleEmployee, models.RoleManager, models.RoleAdmin, models.RoleSuperAdmin} {
+		role := models.OrgRole{
+			OrganizationID: orgID,
+			Name:           base.String(),
+			BaseRole:       base,
+			IsSystem:       true,
+		}
+		for _, perm := range models.DefaultRolePermissions[base] {
+			role.Permissions = append(role.Permissions, models.RolePermission{Permission: perm})
+		}
+		if err := db.Create(&role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rolePermissions(role *models.OrgRole) []models.Permission {
+	perms := make([]models.Permission, len(role.Permissions))
+	for i, rp := range role.Permissions {
+		perms[i] = rp.Permission
+	}
+	return perms
+}
+
+func buildOrgRoleResponse(role *models.OrgRole) models.OrgRoleResponse {
+	response := models.OrgRoleResponse{
+		ID:             role.ID,
+		OrganizationID: role.OrganizationID,
+		Name:           role.Name,
+		BaseRole:       role.BaseRole,
+		IsSystem:       role.IsSystem,
+		Permissions:    rolePermissions(role),
+		CreatedAt:      role.CreatedAt,
+	}
+
+	query := database.DB.Model(&models.Membership{}).Where("organization_id = ?", role.OrganizationID)
+	if role.IsSystem {
+		query = query.Where("org_role_id IS NULL AND role = ?", role.BaseRole)
+	} else {
+		query = query.Where("org_role_id = ?", role.ID)
+	}
+	query.Count(&response.MembersCount)
+
+	return response
+}
+
+func validatePermissions(perms []models.Permission) (string, bool) {
+	for _, perm := range perms {
+		if !models.IsValidPermission(perm) {
+			return string(perm), false
+		}
+	}
+	return "", true
+}
+
+// ungrantablePermission returns the first permission the principal does not
+// hold itself. Roles may only grant what their editor has, otherwise a
+// role.manage holder could give themselves any permission.
+func ungrantablePermission(principal *middleware.Principal, perms []models.Permission) (models.Permission, bool) {
+	for _, perm := range perms {
+		if !principal.Can(perm) {
+			return perm, true
+		}
+	}
+	return "", false
+}
+
+func GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AllPermissions)
+}
+
+func GetOrganizationRoles(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusOK, []models.OrgRoleResponse{})
+		return
+	}
+
+	if err := ensureSystemRoles(database.DB, *principal.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare roles"})
+		return
+	}
+
+	var roles []models.OrgRole
+	if err := database.DB.Preload("Permissions").
+		Where("organization_id = ?", *principal.OrganizationID).
+		Order("is_system desc, base_role asc, name asc").
+		Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	response := make([]models.OrgRoleResponse, len(roles))
+	for i := range roles {
+		response[i] = buildOrgRoleResponse(&roles[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func CreateOrganizationRole(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in an organization"})
+		return
+	}
+
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name is required"})
+		return
+	}
+
+	baseRole := models.RoleUser
+	if input.BaseRole != nil {
+		baseRole = *input.BaseRole
+	}
+	if baseRole < models.RoleUser || baseRole >= models.RoleSuperAdmin || baseRole > principal.Role {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid base role"})
+		return
+	}
+
+	if perm, ok := validatePermissions(input.Permissions); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + perm})
+		return
+	}
+	if perm, found := ungrantablePermission(principal, input.Permissions); found {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant a permission you do not have: " + string(perm)})
+		return
+	}
+
+	if err := ensureSystemRoles(database.DB, *principal.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare roles"})
+		return
+	}
+
+	var existingCount int64
+	database.DB.Model(&models.OrgRole{}).
+		Where("organization_id = ? AND LOWER(name) = LOWER(?)", *principal.OrganizationID, name).
+		Count(&existingCount)
+	if existingCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role with this name already exists"})
+		return
+	}
+
+	role := models.OrgRole{
+		OrganizationID: *principal.OrganizationID,
+		Name:           name,
+		BaseRole:       baseRole,
+	}
+	for _, perm := range input.Permissions {
+		role.Permissions = append(role.Permissions, models.RolePermission{Permission: perm})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		recordAudit(c, tx, role.OrganizationID, models.AuditRoleCreated, "role", role.ID, nil,
+			gin.H{"name": role.Name, "base_role": role.BaseRole, "permissions": rolePermissions(&role)})
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildOrgRoleResponse(&role))
+}
+
+func UpdateOrganizationRole(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	roleID := c.Param("id")
+
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.OrgRole
+	if err := database.DB.Preload("Permissions").First(&role, roleID).Error; err != nil || !principal.InOrganization(role.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if role.IsSystem && role.BaseRole == models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Super Admin permissions cannot be changed"})
+		return
+	}
+	if role.BaseRole > principal.Role {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot edit a role ranked above your own"})
+		return
+	}
+
+	if perm, ok := validatePermissions(input.Permissions); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + perm})
+		return
+	}
+	if perm, found := ungrantablePermission(principal, input.Permissions); found {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant a permission you do not have: " + string(perm)})
+		return
+	}
+
+	before := gin.H{"name": role.Name, "permissions": rolePermissions(&role)}
+
+	name := strings.TrimSpace(input.Name)
+	if name != "" && !role.IsSystem && !strings.EqualFold(name, role.Name) {
+		var existingCount int64
+		database.DB.Model(&models.OrgRole{}).
+			Where("organization_id = ? AND LOWER(name) = LOWER(?) AND id != ?", role.OrganizationID, name, role.ID).
+			Count(&existingCount)
+		if existingCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role with this name already exists"})
+			return
+		}
+		role.Name = name
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Model(&role).Update("name", role.Name).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	if input.Permissions != nil {
+		if err := tx.Where("org_role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update permissions"})
+			return
+		}
+		role.Permissions = nil
+		for _, perm := range input.Permissions {
+			rp := models.RolePermission{OrgRoleID: role.ID, Permission: perm}
+			if err := tx.Create(&rp).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update permissions"})
+				return
+			}
+			role.Permissions = append(role.Permissions, rp)
+		}
+	}
+
+	if err := middleware.BumpOrganizationPrincipals(tx, role.OrganizationID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	recordAudit(c, tx, role.OrganizationID, models.AuditRoleUpdated, "role", role.ID, before,
+		gin.H{"name": role.Name, "permissions": rolePermissions(&role)})
+
+	tx.Commit()
+	c.JSON(http.StatusOK, buildOrgRoleResponse(&role))
+}
+
+func DeleteOrganizationRole(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	roleID := c.Param("id")
+
+	var role models.OrgRole
+	if err := database.DB.Preload("Permissions").First(&role, roleID).Error; err != nil || !principal.InOrganization(role.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if role.IsSystem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "System roles cannot be deleted"})
+		return
+	}
+	if role.BaseRole > principal.Role {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete a role ranked above your own"})
+		return
+	}
+
+	var affectedUserIDs []uint
+	database.DB.Model(&models.Membership{}).Where("org_role_id = ?", role.ID).Pluck("user_id", &affectedUserIDs)
+
+	tx := database.DB.Begin()
+	if err := tx.Model(&models.Membership{}).Where("org_role_id = ?", role.ID).Update("org_role_id", gorm.Expr("NULL")).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update members"})
+		return
+	}
+	for _, userID := range affectedUserIDs {
+		if err := syncActiveMembership(tx, userID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update members"})
+			return
+		}
+	}
+	if err := tx.Where("org_role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if err := tx.Delete(&role).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if err := middleware.BumpOrganizationPrincipals(tx, role.OrganizationID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	recordAudit(c, tx, role.OrganizationID, models.AuditRoleDeleted, "role", role.ID,
+		gin.H{"name": role.Name, "base_role": role.BaseRole, "permissions": rolePermissions(&role), "members": affectedUserIDs}, nil)
+
+	tx.Commit()
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"corp-portal/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+func createSession(c *gin.Context, user models.User) (tokenPair, error) {
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        c.Request.UserAgent(),
+		IP:               c.ClientIP(),
+		ExpiresAt:        time.Now().Add(utils.RefreshTokenTTL),
+		LastUsedAt:       time.Now(),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return tokenPair{}, err
+	}
+
+	accessToken, err := utils.GenerateToken(user, session.ID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	return tokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// signIn finishes a password or identity provider login. Users with 2FA get
+// a challenge to answer at /login/2fa instead of a session.
+func signIn(c *gin.Context, user models.User) {
+	if user.TOTPEnabledAt != nil {
+		challenge, err := issueUserToken(database.DB, user.ID, models.TokenLoginChallenge, loginChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(loginChallengeTTL.Seconds()),
+		})
+		return
+	}
+	startSession(c, user)
+}
+
+func startSession(c *gin.Context, user models.User) {
+	tokens, err := createSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	loginLockout.Reset(loginKey(user.Email))
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+		"user": gin.H{
+			"id":         user.ID,
+			"full_name":  user.FullName,
+			"role":       user.Role,
+			"email":      user.Email,
+			"avatar_url": user.AvatarURL,
+		},
+	})
+}
+
+func revokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func RefreshToken(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	var session models.Session
+	if err := tx.Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", utils.HashToken(input.RefreshToken), time.Now()).
+		First(&session).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	var user models.User
+	if err := tx.First(&user, session.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	result := tx.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": refreshHash,
+			"expires_at":         time.Now().Add(utils.RefreshTokenTTL),
+			"last_used_at":       time.Now(),
+			"ip":                 c.ClientIP(),
+			"user_agent":         c.Request.UserAgent(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	accessToken, err := utils.GenerateToken(user, session.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	tx.Commit()
+	c.JSON(http.StatusOK, gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+func Logout(c *gin.Context) {
+	sessionID := c.MustGet("sessionID").(uint)
+
+	if err := database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+func LogoutAll(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := revokeUserSessions(database.DB, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SprintInput struct {
+	Name      *string `json:"name"`
+	Goal      *string `json:"goal"`
+	StartDate *string `json:"start_date"`
+	EndDate   *string `json:"end_date"`
+}
+
+type SprintRolloverInput struct {
+	// NextSprintID receives the unfinished tasks; empty moves them to the backlog.
+	NextSprintID *uint `json:"next_sprint_id"`
+}
+
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining int     `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+}
+
+func canManageSprints(principal *middleware.Principal, teamID uint) bool {
+	return actsAsTeamLeader(principal, teamID)
+}
+
+func loadAccessibleSprint(c *gin.Context) (*models.Sprint, bool) {
+	principal := middleware.GetPrincipal(c)
+
+	var sprint models.Sprint
+	if err := database.DB.First(&sprint, c.Param("id")).Error; err != nil ||
+		!principal.InOrganization(sprint.OrganizationID) ||
+		(!principal.Can(models.PermTaskViewAll) && !principal.CanSeeTeam(sprint.TeamID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sprint not found"})
+		return nil, false
+	}
+	return &sprint, true
+}
+
+// loadManagedSprint is loadAccessibleSprint for changes: it also requires the
+// caller to manage the sprint's team.
+func loadManagedSprint(c *gin.Context) (*models.Sprint, bool) {
+	sprint, ok := loadAccessibleSprint(c)
+	if !ok {
+		return nil, false
+	}
+	if !canManageSprints(middleware.GetPrincipal(c), sprint.TeamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Team Leader or Manager can manage sprints"})
+		return nil, false
+	}
+	return sprint, true
+}
+
+// validateTaskSprint checks that a task of the team may be put into the sprint.
+func validateTaskSprint(db *gorm.DB, teamID uint, sprintID uint) (*uint, error) {
+	if sprintID == 0 {
+		return nil, nil
+	}
+	var sprint models.Sprint
+	if err := db.Where("id = ? AND team_id = ?", sprintID, teamID).First(&sprint).Error; err != nil {
+		return nil, errors.New("Sprint must belong to the task's team")
+	}
+	if sprint.Status == models.SprintClosed {
+		return nil, errors.New("Sprint is closed")
+	}
+	return &sprint.ID, nil
+}
+
+// rolloverSprintTasks moves the sprint's unfinished tasks to next, or to the
+// backlog when next is nil, and returns how many were moved.
+func rolloverSprintTasks(tx *gorm.DB, sprint *models.Sprint, next *models.Sprint, actorID uint) (int, error) {
+	var tasks []models.Task
+	if err := tx.Where("sprint_id = ? AND status != ?", sprint.ID, models.StatusDone).Find(&tasks).Error; err != nil {
+		return 0, err
+	}
+
+	var to *uint
+	if next != nil {
+		to = &next.ID
+	}
+	from := formatIDRef(&sprint.ID)
+	for _, task := range tasks {
+		if err := tx.Model(&task).Update("sprint_id", to).Error; err != nil {
+			return 0, err
+		}
+		recordTaskActivity(tx, task.ID, actorID, models.ActivitySprintChanged, from, formatIDRef(to))
+	}
+	return len(tasks), nil
+}
+
+func loadRolloverTarget(db *gorm.DB, sprint *models.Sprint, nextSprintID *uint) (*models.Sprint, error) {
+	if nextSprintID == nil || *nextSprintID == 0 {
+		return nil, nil
+	}
+	if *nextSprintID == sprint.ID {
+		return nil, errors.New("Cannot roll a sprint over into itself")
+	}
+	var next models.Sprint
+	if err := db.Where("id = ? AND team_id = ?", *nextSprintID, sprint.TeamID).First(&next).Error; err != nil {
+		return nil, errors.New("Next sprint must belong to the same team")
+	}
+	if next.Status == models.SprintClosed {
+		return nil, errors.New("Next sprint is closed")
+	}
+	return &next, nil
+}
+
+// applySprintInput copies the input onto the sprint and validates the result.
+func applySprintInput(sprint *models.Sprint, input *SprintInput) error {
+	if input.Name != nil {
+		sprint.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Goal != nil {
+		sprint.Goal = strings.TrimSpace(*input.Goal)
+	}
+	if input.StartDate != nil {
+		day, err := parseDay(*input.StartDate)
+		if err != nil {
+			return err
+		}
+		sprint.StartDate = day
+	}
+	if input.EndDate != nil {
+		day, err := parseDay(*input.EndDate)
+		if err != nil {
+			return err
+		}
+		sprint.EndDate = day
+	}
+
+	if sprint.Name == "" {
+		return errors.New("Name is required")
+	}
+	if sprint.StartDate.IsZero() || sprint.EndDate.IsZero() {
+		return errors.New("start_date and end_date are required")
+	}
+	if sprint.EndDate.Before(sprint.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+func GetTeamSprints(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("id")).Error; err != nil || !principal.InOrganization(team.OrganizationID) ||
+		(!principal.Can(models.PermTaskViewAll) && !principal.CanSeeTeam(team.ID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	db := database.DB.Where("team_id = ?", team.ID)
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status IN ?", strings.Split(status, ","))
+	}
+
+	var sprints []models.Sprint
+	if err := db.Order("start_date desc, id desc").Find(&sprints).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sprints"})
+		return
+	}
+	c.JSON(http.StatusOK, sprints)
+}
+
+func CreateSprint(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("id")).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	if !canManageSprints(principal, team.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Team Leader or Manager can manage sprints"})
+		return
+	}
+
+	var input SprintInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sprint := models.Sprint{
+		OrganizationID: team.OrganizationID,
+		TeamID:         team.ID,
+		Status:         models.SprintPlanned,
+	}
+	if err := applySprintInput(&sprint, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&sprint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sprint"})
+		return
+	}
+	c.JSON(http.StatusCreated, sprint)
+}
+
+func UpdateSprint(c *gin.Context) {
+	sprint, ok := loadManagedSprint(c)
+	if !ok {
+		return
+	}
+	if sprint.Status == models.SprintClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sprint is closed"})
+		return
+	}
+
+	var input SprintInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applySprintInput(sprint, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(sprint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sprint"})
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+// DeleteSprint removes a sprint that has not started; its tasks go back to the backlog.
+func DeleteSprint(c *gin.Context) {
+	sprint, ok := loadManagedSprint(c)
+	if !ok {
+		return
+	}
+	if sprint.Status != models.SprintPlanned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only planned sprints can be deleted"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("sprint_id = ?", sprint.ID).Update("sprint_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(sprint).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sprint"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sprint deleted"})
+}
+
+func StartSprint(c *gin.Context) {
+	sprint, ok := loadManagedSprint(c)
+	if !ok {
+		return
+	}
+	if sprint.Status != models.SprintPlanned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only planned sprints can be started"})
+		return
+	}
+
+	// One conditional update, so two sprints of a team cannot be started at once.
+	now := time.Now()
+	activeSprint := database.DB.Model(&models.Sprint{}).Select("1").
+		Where("team_id = ? AND status = ?", sprint.TeamID, models.SprintActive)
+	result := database.DB.Model(sprint).
+		Where("status = ? AND NOT EXISTS (?)", models.SprintPlanned, activeSprint).
+		Updates(map[string]interface{}{"status": models.SprintActive, "started_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sprint"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The team already has an active sprint"})
+		return
+	}
+	sprint.Status = models.SprintActive
+	sprint.StartedAt = &now
+	c.JSON(http.StatusOK, sprint)
+}
+
+// CloseSprint closes the active sprint and rolls its unfinished tasks over
+// to next_sprint_id, or to the backlog if none is given.
+func CloseSprint(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	sprint, ok := loadManagedSprint(c)
+	if !ok {
+		return
+	}
+	if sprint.Status != models.SprintActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active sprints can be closed"})
+		return
+	}
+
+	var input SprintRolloverInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	next, err := loadRolloverTarget(database.DB, sprint, input.NextSprintID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved := 0
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if moved, err = rolloverSprintTasks(tx, sprint, next, principal.ID()); err != nil {
+			return err
+		}
+		return tx.Model(sprint).Updates(map[string]interface{}{"status": models.SprintClosed, "closed_at": now}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close sprint"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sprint": sprint, "moved_tasks": moved})
+}
+
+// RolloverSprint moves unfinished tasks to another sprint without closing this one.
+func RolloverSprint(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	sprint, ok := loadManagedSprint(c)
+	if !ok {
+		return
+	}
+
+	var input SprintRolloverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	next, err := loadRolloverTarget(database.DB, sprint, input.NextSprintID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved := 0
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		moved, err = rolloverSprintTasks(tx, sprint, next, principal.ID())
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"moved_tasks": moved})
+}
+
+// GetSprintBurndown returns, for every day of the sprint up to today, how many
+// of the sprint's tasks were not DONE at the end of that day. Past statuses
+// are replayed from the tasks' status_changed activity.
+func GetSprintBurndown(c *gin.Context) {
+	sprint, ok := loadAccessibleSprint(c)
+	if !ok {
+		return
+	}
+
+	var tasks []models.Task
+	if err := database.DB.Where("sprint_id = ?", sprint.ID).Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+
+	taskIDs := make([]uint, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+	var changes []models.TaskActivity
+	if len(taskIDs) > 0 {
+		if err := database.DB.Where("task_id IN ? AND action = ?", taskIDs, models.ActivityStatusChanged).
+			Order("created_at asc, id asc").
+			Find(&changes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+			return
+		}
+	}
+	changesByTask := make(map[uint][]models.TaskActivity)
+	for _, change := range changes {
+		changesByTask[change.TaskID] = append(changesByTask[change.TaskID], change)
+	}
+
+	statusAt := func(task *models.Task, moment time.Time) (models.TaskStatus, bool) {
+		if task.CreatedAt.After(moment) {
+			return "", false
+		}
+		history := changesByTask[task.ID]
+		if len(history) == 0 {
+			return task.Status, true
+		}
+		status := models.TaskStatus(history[0].From)
+		for _, change := range history {
+			if change.CreatedAt.After(moment) {
+				break
+			}
+			status = models.TaskStatus(change.To)
+		}
+		return status, true
+	}
+
+	last := sprint.EndDate
+	if today := startOfDay(time.Now()); today.Before(last) {
+		last = today
+	}
+	if sprint.ClosedAt != nil && startOfDay(*sprint.ClosedAt).Before(last) {
+		last = startOfDay(*sprint.ClosedAt)
+	}
+
+	totalDays := int(sprint.EndDate.Sub(sprint.StartDate).Hours()/24) + 1
+	points := []BurndownPoint{}
+	for day, i := sprint.StartDate, 0; !day.After(last); day, i = day.AddDate(0, 0, 1), i+1 {
+		endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		remaining := 0
+		for j := range tasks {
+			if status, exists := statusAt(&tasks[j], endOfDay); exists && status != models.StatusDone {
+				remaining++
+			}
+		}
+		ideal := float64(len(tasks))
+		if totalDays > 1 {
+			ideal = ideal * float64(totalDays-1-i) / float64(totalDays-1)
+		}
+		points = append(points, BurndownPoint{
+			Date:      day.Format("2006-01-02"),
+			Remaining: remaining,
+			Ideal:     math.Round(ideal*100) / 100,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sprint":      sprint,
+		"total_tasks": len(tasks),
+		"points":      points,
+	})
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
 	"corp-portal/internal/models"
 	"net/http"
 	"time"
@@ -9,41 +11,44 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// GetTasks lists tasks of one team (team_id) or of every team the caller can
+// see in the active organization. See findTasks for filters and paging.
 func GetTasks(c *gin.Context) {
-	teamID := c.Query("team_id")
-	requestorID, _ := c.Get("userID")
-	requestorRole, _ := c.Get("role")
+	principal := middleware.GetPrincipal(c)
 
-	var currentAdmin models.User
-	database.DB.First(&currentAdmin, requestorID)
+	if teamID := c.Query("team_id"); teamID != "" {
+		var targetTeam models.Team
+		if err := database.DB.First(&targetTeam, teamID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
 
-	var targetTeam models.Team
-	if err := database.DB.First(&targetTeam, teamID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		if !principal.InOrganization(targetTeam.OrganizationID) || (!principal.Can(models.PermTaskViewAll) && !principal.CanSeeTeam(targetTeam.ID)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		findTasks(c, principal, database.DB.Model(&models.Task{}).Where("tasks.team_id = ?", targetTeam.ID))
 		return
 	}
 
-	if requestorRole.(models.Role) < 2 && *currentAdmin.TeamID != targetTeam.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusOK, []models.Task{})
 		return
 	}
-	var tasks []models.Task
-	err := database.DB.Where("team_id = ?", teamID).
-		Preload("Assignee").
-		Preload("Creator").
-		Order("created_at desc").
-		Find(&tasks).Error
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
-		return
+	db := database.DB.Model(&models.Task{}).Where("tasks.organization_id = ?", *principal.OrganizationID)
+	if !principal.Can(models.PermTaskViewAll) {
+		if len(principal.VisibleTeamIDs) > 0 {
+			db = db.Where("(tasks.team_id IN ? OR tasks.assignee_id = ?)", principal.VisibleTeamIDs, principal.ID())
+		} else {
+			db = db.Where("tasks.assignee_id = ?", principal.ID())
+		}
 	}
-	c.JSON(http.StatusOK, tasks)
+	findTasks(c, principal, db)
 }
 
 func CreateTask(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var input struct {
 		Title       string              `json:"title" binding:"required"`
@@ -52,6 +57,9 @@ func CreateTask(c *gin.Context) {
 		DueDate     *time.Time          `json:"due_date"`
 		AssigneeID  *uint               `json:"assignee_id"`
 		TeamID      uint                `json:"team_id" binding:"required"`
+		ParentID    uint                `json:"parent_id"`
+		Estimate    *int                `json:"estimate_minutes"`
+		SprintID    uint                `json:"sprint_id"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -59,56 +67,254 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	database.DB.First(&user, userID)
+	var team models.Team
+	if err := database.DB.First(&team, input.TeamID).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
 
-	isTeamLeader := user.TeamID != nil && *user.TeamID == input.TeamID && role >= 1
-	if !isTeamLeader && role < 2 {
+	isTeamLeader := principal.InTeam(input.TeamID) && principal.Can(models.PermTaskCreateOwnTeam)
+	if !isTeamLeader && !principal.Can(models.PermTaskCreate) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only leaders can create tasks"})
 		return
 	}
 
+	workflow, err := loadWorkflow(database.DB, team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+	if input.Priority == "" {
+		input.Priority = models.PriorityMedium
+	}
+	if !isValidTaskPriority(input.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority"})
+		return
+	}
+	if input.Estimate != nil && *input.Estimate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estimate cannot be negative"})
+		return
+	}
+
 	task := models.Task{
-		Title:          input.Title,
-		Description:    input.Description,
-		Priority:       input.Priority,
-		DueDate:        input.DueDate,
-		AssigneeID:     input.AssigneeID,
-		TeamID:         input.TeamID,
-		CreatorID:      userID,
-		OrganizationID: *user.OrganizationID,
-		Status:         models.StatusTodo,
+		Title:           input.Title,
+		Description:     input.Description,
+		Priority:        input.Priority,
+		DueDate:         input.DueDate,
+		EstimateMinutes: input.Estimate,
+		AssigneeID:      input.AssigneeID,
+		TeamID:          input.TeamID,
+		CreatorID:       principal.ID(),
+		OrganizationID:  team.OrganizationID,
+		Status:          workflow.InitialStatus(),
+	}
+
+	parentID, err := validateTaskParent(database.DB, &task, input.ParentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	task.ParentID = parentID
+
+	sprintID, err := validateTaskSprint(database.DB, task.TeamID, input.SprintID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	task.SprintID = sprintID
 
 	if err := database.DB.Create(&task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
+	recordTaskActivity(database.DB, task.ID, principal.ID(), models.ActivityCreated, "", task.Title)
+	notifyTaskAssigned(principal, &task)
+	events.Publish(events.TaskEvent(events.TaskCreated, &task, task))
 	c.JSON(http.StatusCreated, task)
 }
 
-func UpdateTask(c *gin.Context) {
-	taskID := c.Param("id")
+func isValidTaskPriority(priority models.TaskPriority) bool {
+	switch priority {
+	case models.PriorityLow, models.PriorityMedium, models.PriorityHigh:
+		return true
+	}
+	return false
+}
+
+func canAccessTask(principal *middleware.Principal, task *models.Task) bool {
+	return principal.InOrganization(task.OrganizationID) &&
+		(principal.Can(models.PermTaskViewAll) || principal.CanSeeTeam(task.TeamID) ||
+			(task.AssigneeID != nil && *task.AssigneeID == principal.ID()))
+}
+
+// actsAsTeamLeader reports whether the principal counts as a leader of the
+// team: its leader or deputy, or an org-wide task manager who can see the
+// team.
+func actsAsTeamLeader(principal *middleware.Principal, teamID uint) bool {
+	return principal.ManagesTeam(teamID) ||
+		(principal.Can(models.PermTaskCreate) && (principal.Can(models.PermTaskViewAll) || principal.CanSeeTeam(teamID)))
+}
+
+func actsAsTaskLeader(principal *middleware.Principal, task *models.Task) bool {
+	return actsAsTeamLeader(principal, task.TeamID)
+}
+
+// canEditTask reports whether the principal may change the task's fields
+// other than its status, which the team workflow governs.
+func canEditTask(principal *middleware.Principal, task *models.Task) bool {
+	return task.CreatorID == principal.ID() ||
+		(task.AssigneeID != nil && *task.AssigneeID == principal.ID()) ||
+		actsAsTaskLeader(principal, task)
+}
+
+// loadAccessibleTask loads the task from the :id param and writes an error
+// response if the principal cannot see it.
+func loadAccessibleTask(c *gin.Context) (*models.Task, bool) {
 	var task models.Task
-	if err := database.DB.First(&task, taskID).Error; err != nil {
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil || !canAccessTask(middleware.GetPrincipal(c), &task) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	}
+	return &task, true
+}
+
+// loadEditableTask is loadAccessibleTask for changes to the task's content.
+// It responds 403 when the principal can see the task but not edit it.
+func loadEditableTask(c *gin.Context) (*models.Task, bool) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return nil, false
+	}
+	if !canEditTask(middleware.GetPrincipal(c), task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator, the assignee or a team leader can edit this task"})
+		return nil, false
+	}
+	return task, true
+}
+
+func UpdateTask(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&task); err != nil {
+	// Fields missing from the body keep their values; explicit nulls clear them.
+	changes := *task
+	if err := c.ShouldBindJSON(&changes); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !canEditTask(principal, task) {
+		fields := changes
+		fields.Status = task.Status
+		if fields.Description != task.Description || len(diffTask(task, &fields)) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator, the assignee or a team leader can edit this task"})
+			return
+		}
+	}
 
+	if !isValidTaskPriority(changes.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority"})
+		return
+	}
+	if changes.EstimateMinutes != nil && *changes.EstimateMinutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estimate cannot be negative"})
+		return
+	}
+	if changes.Status != task.Status {
+		workflow, err := loadWorkflow(database.DB, task.TeamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+			return
+		}
+		if reason := checkTransition(principal, &workflow, task, changes.Status); reason != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": reason})
+			return
+		}
+		if changes.Status == models.StatusDone {
+			blockers, err := openBlockers(database.DB, task.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check blockers"})
+				return
+			}
+			if len(blockers) > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": describeBlockers(blockers)})
+				return
+			}
+		}
+	}
+	if !sameTeamRef(changes.ParentID, task.ParentID) {
+		var parentID uint
+		if changes.ParentID != nil {
+			parentID = *changes.ParentID
+		}
+		validated, err := validateTaskParent(database.DB, task, parentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changes.ParentID = validated
+	}
+	if !sameTeamRef(changes.SprintID, task.SprintID) {
+		var sprintID uint
+		if changes.SprintID != nil {
+			sprintID = *changes.SprintID
+		}
+		validated, err := validateTaskSprint(database.DB, task.TeamID, sprintID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		changes.SprintID = validated
+	}
+	if changes.AssigneeID != nil && !sameTeamRef(changes.AssigneeID, task.AssigneeID) && !isMember(*changes.AssigneeID, task.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee must be a member of the organization"})
+		return
+	}
+
+	activity := diffTask(task, &changes)
+	assigneeChanged := !sameTeamRef(task.AssigneeID, changes.AssigneeID)
+	previousAssigneeID := task.AssigneeID
+
+	task.Title = changes.Title
+	task.Description = changes.Description
+	task.Status = changes.Status
+	task.Priority = changes.Priority
+	task.DueDate = changes.DueDate
+	task.EstimateMinutes = changes.EstimateMinutes
+	task.AssigneeID = changes.AssigneeID
+	task.ParentID = changes.ParentID
+	task.SprintID = changes.SprintID
 	task.UpdatedAt = time.Now()
-	database.DB.Save(&task)
+
+	tx := database.DB.Begin()
+	if err := tx.Save(task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	for _, entry := range activity {
+		entry.TaskID = task.ID
+		entry.ActorID = principal.ID()
+		if err := tx.Create(&entry).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+	}
+	tx.Commit()
+
+	if assigneeChanged {
+		notifyTaskAssigned(principal, task)
+	}
+	events.Publish(events.TaskEvent(events.TaskUpdated, task, *task, previousAssigneeID))
 	c.JSON(http.StatusOK, task)
 }
 
 func DeleteTask(c *gin.Context) {
 	taskID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-	role := c.MustGet("role").(models.Role)
+	principal := middleware.GetPrincipal(c)
 
 	var task models.Task
 	if err := database.DB.First(&task, taskID).Error; err != nil {
@@ -116,11 +322,8 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	database.DB.First(&user, userID)
-
-	isTeamLeader := user.TeamID != nil && *user.TeamID == task.TeamID && role >= 1
-	isAdmin := role >= 2
+	isTeamLeader := principal.InTeam(task.TeamID) && principal.Can(models.PermTaskDeleteOwnTeam)
+	isAdmin := principal.InOrganization(task.OrganizationID) && principal.Can(models.PermTaskDelete)
 
 	if !isTeamLeader && !isAdmin {
 		c.JSON(http.StatusForbidden, gin.H{"error": "У вас нет прав для удаления этой задачи"})
@@ -131,6 +334,19 @@ func DeleteTask(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
 	}
+	database.DB.Where("task_id = ?", task.ID).Delete(&models.TaskComment{})
+	database.DB.Where("task_id = ?", task.ID).Delete(&models.TaskActivity{})
+	database.DB.Where("task_id = ?", task.ID).Delete(&models.TaskChecklistItem{})
+	database.DB.Where("task_id = ?", task.ID).Delete(&models.TaskTimeLog{})
+	if files, err := deleteTaskAttachments(database.DB, task.ID); err == nil {
+		for _, path := range files {
+			removeAttachmentFile(path)
+		}
+	}
+	database.DB.Where("task_id = ? OR blocked_by_id = ?", task.ID, task.ID).Delete(&models.TaskDependency{})
+	// Subtasks move up to the deleted task's parent.
+	database.DB.Model(&models.Task{}).Where("parent_id = ?", task.ID).Update("parent_id", task.ParentID)
+	events.Publish(events.TaskEvent(events.TaskDeleted, &task, gin.H{"id": task.ID, "team_id": task.TeamID}))
 
 	c.JSON(http.StatusOK, gin.H{"message": "Задача успешно удалена"})
 }
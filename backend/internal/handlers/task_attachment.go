@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// deleteTaskAttachments removes the attachment rows of the tasks and returns
+// their file paths so the files can be removed once the change is committed.
+func deleteTaskAttachments(db *gorm.DB, taskIDs ...uint) ([]string, error) {
+	var files []string
+	if err := db.Model(&models.TaskAttachment{}).Where("task_id IN ?", taskIDs).Pluck("file_path", &files).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("task_id IN ?", taskIDs).Delete(&models.TaskAttachment{}).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// removeAttachmentFile deletes a stored attachment file. Paths outside
+// models.AttachmentsDir are ignored.
+func removeAttachmentFile(path string) {
+	if filepath.Dir(path) != filepath.Clean(models.AttachmentsDir) {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func GetTaskAttachments(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var attachments []models.TaskAttachment
+	if err := database.DB.Preload("Uploader").
+		Where("task_id = ?", task.ID).
+		Order("created_at asc").
+		Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachments"})
+		return
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+func UploadTaskAttachment(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	middleware.Upload(middleware.AttachmentUploadConfig(models.AttachmentsDir))(c)
+	if c.IsAborted() {
+		return
+	}
+	if hasFile, _ := c.Get("hasFile"); !hasFile.(bool) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+
+	filePath, _ := c.Get("filePath")
+	originalName, _ := c.Get("originalName")
+	fileSize, _ := c.Get("fileSize")
+	mimeType, _ := c.Get("mimeType")
+
+	attachment := models.TaskAttachment{
+		TaskID:       task.ID,
+		FilePath:     filePath.(string),
+		OriginalName: originalName.(string),
+		MimeType:     mimeType.(string),
+		Size:         fileSize.(int64),
+		UploaderID:   principal.ID(),
+	}
+	if err := database.DB.Create(&attachment).Error; err != nil {
+		removeAttachmentFile(attachment.FilePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to DB"})
+		return
+	}
+
+	uploader := principal.User
+	attachment.Uploader = &uploader
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func DownloadTaskAttachment(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var attachment models.TaskAttachment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("attachmentId"), task.ID).First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	// FormatMediaType quotes the name, or switches to the RFC 2231 form for
+	// control and non-ASCII characters, so the name cannot break the header.
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.OriginalName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(attachment.FilePath)
+}
+
+func DeleteTaskAttachment(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var attachment models.TaskAttachment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("attachmentId"), task.ID).First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+	if attachment.UploaderID != principal.ID() && !canModerateTaskContent(principal, task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := database.DB.Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+	removeAttachmentFile(attachment.FilePath)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChecklistItemInput struct {
+	Title    *string `json:"title"`
+	Done     *bool   `json:"done"`
+	Position *int    `json:"position"`
+}
+
+func GetTaskChecklist(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var items []models.TaskChecklistItem
+	if err := database.DB.Where("task_id = ?", task.ID).Order("position asc, id asc").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch checklist"})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func CreateChecklistItem(c *gin.Context) {
+	task, ok := loadEditableTask(c)
+	if !ok {
+		return
+	}
+
+	var input ChecklistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Title == nil || strings.TrimSpace(*input.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	item := models.TaskChecklistItem{TaskID: task.ID, Title: strings.TrimSpace(*input.Title)}
+	if input.Done != nil {
+		item.Done = *input.Done
+	}
+	if input.Position != nil {
+		item.Position = *input.Position
+	} else {
+		var count int64
+		database.DB.Model(&models.TaskChecklistItem{}).Where("task_id = ?", task.ID).Count(&count)
+		item.Position = int(count)
+	}
+
+	if err := database.DB.Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add checklist item"})
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func UpdateChecklistItem(c *gin.Context) {
+	task, ok := loadEditableTask(c)
+	if !ok {
+		return
+	}
+
+	var item models.TaskChecklistItem
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+
+	var input ChecklistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+			return
+		}
+		updates["title"] = title
+	}
+	if input.Done != nil {
+		updates["done"] = *input.Done
+	}
+	if input.Position != nil {
+		updates["position"] = *input.Position
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(&item).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update checklist item"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func DeleteChecklistItem(c *gin.Context) {
+	task, ok := loadEditableTask(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).Delete(&models.TaskChecklistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete checklist item"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist item deleted"})
+}
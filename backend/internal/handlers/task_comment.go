@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxCommentLength = 10000
+
+type TaskCommentInput struct {
+	Body string `json:"body" binding:"required"`
+}
+
+func recordTaskActivity(db *gorm.DB, taskID, actorID uint, action models.TaskActivityAction, from, to string) {
+	db.Create(&models.TaskActivity{
+		TaskID:  taskID,
+		ActorID: actorID,
+		Action:  action,
+		From:    from,
+		To:      to,
+	})
+}
+
+func formatDueDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatIDRef(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func formatMinutes(minutes *int) string {
+	if minutes == nil {
+		return ""
+	}
+	return strconv.Itoa(*minutes)
+}
+
+// diffTask describes what an update changes, one activity entry per field.
+func diffTask(before, after *models.Task) []models.TaskActivity {
+	var entries []models.TaskActivity
+	add := func(action models.TaskActivityAction, from, to string) {
+		if from != to {
+			entries = append(entries, models.TaskActivity{Action: action, From: from, To: to})
+		}
+	}
+	add(models.ActivityTitleChanged, before.Title, after.Title)
+	add(models.ActivityStatusChanged, string(before.Status), string(after.Status))
+	add(models.ActivityPriorityChanged, string(before.Priority), string(after.Priority))
+	add(models.ActivityAssigneeChanged, formatIDRef(before.AssigneeID), formatIDRef(after.AssigneeID))
+	add(models.ActivityDueDateChanged, formatDueDate(before.DueDate), formatDueDate(after.DueDate))
+	add(models.ActivityParentChanged, formatIDRef(before.ParentID), formatIDRef(after.ParentID))
+	add(models.ActivitySprintChanged, formatIDRef(before.SprintID), formatIDRef(after.SprintID))
+	add(models.ActivityEstimateChanged, formatMinutes(before.EstimateMinutes), formatMinutes(after.EstimateMinutes))
+	return entries
+}
+
+func canModerateTaskContent(principal *middleware.Principal, task *models.Task) bool {
+	return principal.Can(models.PermTaskDelete) ||
+		(principal.InTeam(task.TeamID) && principal.Can(models.PermTaskDeleteOwnTeam))
+}
+
+func GetTaskComments(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var comments []models.TaskComment
+	if err := database.DB.Preload("Author").
+		Where("task_id = ?", task.ID).
+		Order("created_at asc").
+		Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+func CreateTaskComment(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var input TaskCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := strings.TrimSpace(input.Body)
+	if body == "" || len(body) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment must be between 1 and 10000 characters"})
+		return
+	}
+
+	comment := models.TaskComment{
+		TaskID:   task.ID,
+		AuthorID: principal.ID(),
+		Body:     body,
+	}
+	if err := database.DB.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+	recordTaskActivity(database.DB, task.ID, principal.ID(), models.ActivityCommented, "", strconv.FormatUint(uint64(comment.ID), 10))
+
+	author := principal.User
+	comment.Author = &author
+	c.JSON(http.StatusCreated, comment)
+}
+
+func UpdateTaskComment(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var comment models.TaskComment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("commentId"), task.ID).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.AuthorID != principal.ID() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
+	var input TaskCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := strings.TrimSpace(input.Body)
+	if body == "" || len(body) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment must be between 1 and 10000 characters"})
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&comment).Updates(map[string]interface{}{"body": body, "edited_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+	comment.Body = body
+	comment.EditedAt = &now
+	c.JSON(http.StatusOK, comment)
+}
+
+func DeleteTaskComment(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var comment models.TaskComment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("commentId"), task.ID).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.AuthorID != principal.ID() && !canModerateTaskContent(principal, task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := database.DB.Delete(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+func GetTaskActivity(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var activity []models.TaskActivity
+	if err := database.DB.Preload("Actor").
+		Where("task_id = ?", task.ID).
+		Order("created_at asc, id asc").
+		Find(&activity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+	c.JSON(http.StatusOK, activity)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TaskBlockerInput struct {
+	BlockedByID uint `json:"blocked_by_id" binding:"required"`
+}
+
+// blocksTransitively reports whether fromID already waits on targetID through
+// a chain of dependencies, which would turn a new link into a cycle.
+func blocksTransitively(db *gorm.DB, fromID, targetID uint) (bool, error) {
+	seen := map[uint]bool{fromID: true}
+	frontier := []uint{fromID}
+	for len(frontier) > 0 {
+		var next []uint
+		if err := db.Model(&models.TaskDependency{}).Where("task_id IN ?", frontier).Pluck("blocked_by_id", &next).Error; err != nil {
+			return false, err
+		}
+		frontier = frontier[:0:0]
+		for _, id := range next {
+			if id == targetID {
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// GetTaskBlockers lists both directions of the task's dependencies. Blockers
+// from teams the caller cannot see are listed by ID only, since they still
+// keep the task from being done; hidden blocked tasks are left out.
+func GetTaskBlockers(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var blockers []models.TaskDependency
+	if err := database.DB.Preload("BlockedBy").Where("task_id = ?", task.ID).Order("created_at asc").Find(&blockers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blockers"})
+		return
+	}
+	for i := range blockers {
+		if blockers[i].BlockedBy != nil && !canAccessTask(principal, blockers[i].BlockedBy) {
+			blockers[i].BlockedBy = nil
+		}
+	}
+
+	var dependents []models.Task
+	database.DB.Model(&models.Task{}).
+		Joins("JOIN task_dependencies ON task_dependencies.task_id = tasks.id").
+		Where("task_dependencies.blocked_by_id = ?", task.ID).
+		Find(&dependents)
+	blocking := []models.Task{}
+	for i := range dependents {
+		if canAccessTask(principal, &dependents[i]) {
+			blocking = append(blocking, dependents[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocked_by": blockers, "blocking": blocking})
+}
+
+func AddTaskBlocker(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadEditableTask(c)
+	if !ok {
+		return
+	}
+
+	var input TaskBlockerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.BlockedByID == task.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task cannot block itself"})
+		return
+	}
+
+	var blocker models.Task
+	if err := database.DB.First(&blocker, input.BlockedByID).Error; err != nil || !canAccessTask(principal, &blocker) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blocking task not found"})
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.TaskDependency{}).Where("task_id = ? AND blocked_by_id = ?", task.ID, blocker.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Dependency already exists"})
+		return
+	}
+
+	cycle, err := blocksTransitively(database.DB, blocker.ID, task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check dependencies"})
+		return
+	}
+	if cycle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dependency would create a cycle"})
+		return
+	}
+
+	dependency := models.TaskDependency{TaskID: task.ID, BlockedByID: blocker.ID}
+	if err := database.DB.Create(&dependency).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add dependency"})
+		return
+	}
+	recordTaskActivity(database.DB, task.ID, principal.ID(), models.ActivityBlockerAdded, "", strconv.FormatUint(uint64(blocker.ID), 10))
+
+	dependency.BlockedBy = &blocker
+	c.JSON(http.StatusCreated, dependency)
+}
+
+func RemoveTaskBlocker(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadEditableTask(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("task_id = ? AND blocked_by_id = ?", task.ID, c.Param("blockerId")).Delete(&models.TaskDependency{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove dependency"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+		return
+	}
+	recordTaskActivity(database.DB, task.ID, principal.ID(), models.ActivityBlockerRemoved, c.Param("blockerId"), "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency removed"})
+}
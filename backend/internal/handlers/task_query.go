@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 200
+	nextCursorHeader    = "X-Next-Cursor"
+)
+
+const priorityRankSQL = "CASE tasks.priority WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 ELSE 1 END"
+
+type taskSort struct {
+	expr     string
+	nullable bool
+	desc     bool
+}
+
+var taskSorts = map[string]taskSort{
+	"created_at": {expr: "tasks.created_at", desc: true},
+	"priority":   {expr: priorityRankSQL, desc: true},
+	"due_date":   {expr: "tasks.due_date", nullable: true},
+}
+
+// taskCursor points at the last task of a page: its sort value and ID.
+// At is nil for tasks without a due date when sorting by due_date.
+type taskCursor struct {
+	ID   uint       `json:"id"`
+	At   *time.Time `json:"at,omitempty"`
+	Rank int        `json:"rank,omitempty"`
+}
+
+func priorityRank(priority models.TaskPriority) int {
+	switch priority {
+	case models.PriorityHigh:
+		return 3
+	case models.PriorityMedium:
+		return 2
+	}
+	return 1
+}
+
+func encodeTaskCursor(cursor taskCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeTaskCursor(value string) (taskCursor, error) {
+	var cursor taskCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(raw, &cursor) != nil || cursor.ID == 0 {
+		return cursor, errors.New("Invalid cursor")
+	}
+	return cursor, nil
+}
+
+func cursorForTask(sortBy string, task *models.Task) taskCursor {
+	cursor := taskCursor{ID: task.ID}
+	switch sortBy {
+	case "priority":
+		cursor.Rank = priorityRank(task.Priority)
+	case "due_date":
+		cursor.At = task.DueDate
+	default:
+		createdAt := task.CreatedAt
+		cursor.At = &createdAt
+	}
+	return cursor
+}
+
+func parseUserFilter(principal *middleware.Principal, value string) (*uint, bool, error) {
+	switch value {
+	case "me":
+		id := principal.ID()
+		return &id, false, nil
+	case "none":
+		return nil, true, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, false, fmt.Errorf("Invalid user filter %q. Use an id, me or none", value)
+	}
+	uid := uint(id)
+	return &uid, false, nil
+}
+
+// applyTaskFilters narrows the query with the filters from the query string:
+// assignee_id, creator_id, parent_id, sprint_id, status, priority, due_from,
+// due_to, overdue and q.
+func applyTaskFilters(c *gin.Context, principal *middleware.Principal, db *gorm.DB) (*gorm.DB, error) {
+	if value := c.Query("assignee_id"); value != "" {
+		id, none, err := parseUserFilter(principal, value)
+		if err != nil {
+			return nil, err
+		}
+		if none {
+			db = db.Where("tasks.assignee_id IS NULL")
+		} else {
+			db = db.Where("tasks.assignee_id = ?", *id)
+		}
+	}
+
+	if value := c.Query("creator_id"); value != "" {
+		id, none, err := parseUserFilter(principal, value)
+		if err != nil || none {
+			return nil, errors.New("Invalid creator filter. Use an id or me")
+		}
+		db = db.Where("tasks.creator_id = ?", *id)
+	}
+
+	if value := c.Query("parent_id"); value != "" {
+		if value == "none" {
+			db = db.Where("tasks.parent_id IS NULL")
+		} else {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, errors.New("Invalid parent_id. Use an id or none")
+			}
+			db = db.Where("tasks.parent_id = ?", id)
+		}
+	}
+
+	if value := c.Query("sprint_id"); value != "" {
+		if value == "none" {
+			db = db.Where("tasks.sprint_id IS NULL")
+		} else {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, errors.New("Invalid sprint_id. Use an id or none")
+			}
+			db = db.Where("tasks.sprint_id = ?", id)
+		}
+	}
+
+	if value := c.Query("status"); value != "" {
+		statuses := strings.Split(strings.ToUpper(value), ",")
+		db = db.Where("tasks.status IN ?", statuses)
+	}
+
+	if value := c.Query("priority"); value != "" {
+		var priorities []models.TaskPriority
+		for _, p := range strings.Split(strings.ToUpper(value), ",") {
+			priority := models.TaskPriority(strings.TrimSpace(p))
+			if !isValidTaskPriority(priority) {
+				return nil, fmt.Errorf("Invalid priority %q", p)
+			}
+			priorities = append(priorities, priority)
+		}
+		db = db.Where("tasks.priority IN ?", priorities)
+	}
+
+	if value := c.Query("due_from"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New("Invalid due_from, expected YYYY-MM-DD")
+		}
+		db = db.Where("tasks.due_date >= ?", from)
+	}
+	if value := c.Query("due_to"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New("Invalid due_to, expected YYYY-MM-DD")
+		}
+		db = db.Where("tasks.due_date < ?", to.AddDate(0, 0, 1))
+	}
+
+	if c.Query("overdue") == "true" {
+		db = db.Where("tasks.due_date IS NOT NULL AND tasks.due_date < ? AND tasks.status != ?", time.Now(), models.StatusDone)
+	}
+
+	if value := strings.TrimSpace(c.Query("q")); value != "" {
+		query := "%" + value + "%"
+		db = db.Where("(tasks.title LIKE ? OR tasks.description LIKE ?)", query, query)
+	}
+
+	return db, nil
+}
+
+// findTasks applies filters, sorting and cursor pagination to a scoped task
+// query and writes the page. The cursor for the next page, if any, is sent
+// in the X-Next-Cursor header so the body stays a plain list.
+// Without limit or cursor all matching tasks are returned, as the board expects.
+// include=subtree attaches subtasks and completion percentage to every task.
+func findTasks(c *gin.Context, principal *middleware.Principal, db *gorm.DB) {
+	db, err := applyTaskFilters(c, principal, db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort", "created_at")
+	sort, ok := taskSorts[sortBy]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort. Allowed: created_at, priority, due_date"})
+		return
+	}
+	switch c.Query("order") {
+	case "asc":
+		sort.desc = false
+	case "desc":
+		sort.desc = true
+	case "":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order. Allowed: asc, desc"})
+		return
+	}
+
+	direction, op := "asc", ">"
+	if sort.desc {
+		direction, op = "desc", "<"
+	}
+
+	limitParam, cursorParam := c.Query("limit"), c.Query("cursor")
+	paginate := limitParam != "" || cursorParam != ""
+	limit := defaultTaskPageSize
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxTaskPageSize {
+			limit = maxTaskPageSize
+		}
+	}
+
+	if cursorParam != "" {
+		cursor, err := decodeTaskCursor(cursorParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var value interface{} = cursor.Rank
+		if sortBy != "priority" {
+			value = cursor.At
+		}
+		switch {
+		case sort.nullable && cursor.At == nil:
+			db = db.Where(fmt.Sprintf("%s IS NULL AND tasks.id %s ?", sort.expr, op), cursor.ID)
+		case sort.nullable:
+			db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND tasks.id %[2]s ?) OR %[1]s IS NULL)", sort.expr, op), value, value, cursor.ID)
+		default:
+			db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND tasks.id %[2]s ?))", sort.expr, op), value, value, cursor.ID)
+		}
+	}
+
+	if sort.nullable {
+		db = db.Order(sort.expr + " IS NULL")
+	}
+	db = db.Order(sort.expr + " " + direction).Order("tasks.id " + direction)
+	if paginate {
+		db = db.Limit(limit + 1)
+	}
+
+	var tasks []models.Task
+	if err := db.Preload("Assignee").Preload("Creator").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+
+	if paginate && len(tasks) > limit {
+		tasks = tasks[:limit]
+		c.Header(nextCursorHeader, encodeTaskCursor(cursorForTask(sortBy, &tasks[limit-1])))
+	}
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+	if c.Query("include") == "subtree" {
+		if err := loadSubtrees(database.DB, tasks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subtasks"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetMyTasks lists tasks across all teams of the active organization.
+// scope=assigned (default) returns tasks assigned to the caller, scope=managed
+// returns tasks of the teams the caller leads or deputizes, with their sub-teams.
+func GetMyTasks(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusOK, []models.Task{})
+		return
+	}
+
+	db := database.DB.Model(&models.Task{}).Where("tasks.organization_id = ?", *principal.OrganizationID)
+
+	switch c.DefaultQuery("scope", "assigned") {
+	case "assigned":
+		db = db.Where("tasks.assignee_id = ?", principal.ID())
+	case "managed":
+		tree, err := loadTeamTree(database.DB, *principal.OrganizationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load teams"})
+			return
+		}
+		teamIDs := []uint{}
+		for teamID := range principal.TeamRoles {
+			if principal.ManagesTeam(teamID) {
+				teamIDs = append(teamIDs, teamID)
+				teamIDs = append(teamIDs, tree.Descendants(teamID)...)
+			}
+		}
+		if len(teamIDs) == 0 {
+			c.JSON(http.StatusOK, []models.Task{})
+			return
+		}
+		db = db.Where("tasks.team_id IN ?", teamIDs)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope. Allowed: assigned, managed"})
+		return
+	}
+
+	findTasks(c, principal, db)
+}
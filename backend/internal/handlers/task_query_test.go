@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Task{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+}
+
+// listTasks runs findTasks with the query string and returns the task IDs
+// and the next cursor.
+func listTasks(t *testing.T, query url.Values) ([]uint, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/tasks?"+query.Encode(), nil)
+
+	findTasks(c, &middleware.Principal{}, database.DB.Model(&models.Task{}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("findTasks(%s) = %d: %s", query.Encode(), w.Code, w.Body.String())
+	}
+	var tasks []models.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatalf("decode tasks: %v", err)
+	}
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids, w.Header().Get(nextCursorHeader)
+}
+
+func TestFindTasksDueDateCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	day := func(d int) *time.Time {
+		at := time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC)
+		return &at
+	}
+	// Repeated and missing due dates make the ID tie-breaker and the NULL
+	// branch of the cursor predicates matter.
+	dueDates := []*time.Time{day(1), nil, day(2), day(1), nil, day(3), day(2)}
+	for i, due := range dueDates {
+		task := models.Task{Title: "task " + strconv.Itoa(i+1), DueDate: due, OrganizationID: 1, TeamID: 1}
+		if err := database.DB.Create(&task).Error; err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+	}
+
+	tests := []struct {
+		order string
+		want  []uint
+	}{
+		// Tasks without a due date come last in both orders.
+		{"asc", []uint{1, 4, 3, 7, 6, 2, 5}},
+		{"desc", []uint{6, 7, 3, 4, 1, 5, 2}},
+	}
+	for _, tt := range tests {
+		all, cursor := listTasks(t, url.Values{"sort": {"due_date"}, "order": {tt.order}})
+		if !reflect.DeepEqual(all, tt.want) || cursor != "" {
+			t.Fatalf("order=%s without paging = %v (cursor %q), want %v", tt.order, all, cursor, tt.want)
+		}
+
+		for _, limit := range []int{1, 2, 3, 6, 7, 10} {
+			t.Run(tt.order+"/limit="+strconv.Itoa(limit), func(t *testing.T) {
+				var got []uint
+				query := url.Values{"sort": {"due_date"}, "order": {tt.order}, "limit": {strconv.Itoa(limit)}}
+				for page := 0; ; page++ {
+					if page > len(tt.want) {
+						t.Fatalf("paging did not stop, got %v", got)
+					}
+					ids, next := listTasks(t, query)
+					if len(ids) > limit {
+						t.Fatalf("page %d has %d tasks, limit %d", page, len(ids), limit)
+					}
+					got = append(got, ids...)
+					if next == "" {
+						break
+					}
+					query.Set("cursor", next)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("pages = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/scheduler"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func canManageRecurrences(principal *middleware.Principal, teamID uint) bool {
+	return (principal.InTeam(teamID) && principal.Can(models.PermTaskCreateOwnTeam)) || principal.Can(models.PermTaskCreate)
+}
+
+// prepareRecurrence validates the template and normalizes its rule and times.
+func prepareRecurrence(recurrence *models.TaskRecurrence) (models.RecurrenceRule, error) {
+	recurrence.Title = strings.TrimSpace(recurrence.Title)
+	if recurrence.Title == "" {
+		return models.RecurrenceRule{}, errors.New("Title is required")
+	}
+	if recurrence.Priority == "" {
+		recurrence.Priority = models.PriorityMedium
+	}
+	if !isValidTaskPriority(recurrence.Priority) {
+		return models.RecurrenceRule{}, errors.New("Invalid priority")
+	}
+	if recurrence.DueInDays < 0 || recurrence.DueInDays > 365 {
+		return models.RecurrenceRule{}, errors.New("due_in_days must be between 0 and 365")
+	}
+	if recurrence.AssigneeID != nil && !isMember(*recurrence.AssigneeID, recurrence.OrganizationID) {
+		return models.RecurrenceRule{}, errors.New("Assignee must be a member of the organization")
+	}
+
+	rule, err := models.ParseRecurrenceRule(recurrence.Rule)
+	if err != nil {
+		return rule, err
+	}
+	recurrence.Rule = rule.String()
+
+	if recurrence.StartsAt.IsZero() {
+		recurrence.StartsAt = time.Now()
+	}
+	recurrence.StartsAt = recurrence.StartsAt.UTC().Truncate(time.Second)
+	if recurrence.Until != nil {
+		until := recurrence.Until.UTC()
+		if until.Before(recurrence.StartsAt) {
+			return rule, errors.New("until must be after starts_at")
+		}
+		recurrence.Until = &until
+	}
+	return rule, nil
+}
+
+// scheduleRecurrence sets the next run from now on, never repeating an
+// occurrence that already produced a task.
+func scheduleRecurrence(recurrence *models.TaskRecurrence, rule models.RecurrenceRule) {
+	if !recurrence.Active {
+		recurrence.NextRunAt = nil
+		return
+	}
+	after := time.Now().UTC().Add(-time.Second)
+	if recurrence.LastRunAt != nil && recurrence.LastRunAt.After(after) {
+		after = *recurrence.LastRunAt
+	}
+	recurrence.NextRunAt = scheduler.NextRunAt(recurrence, rule, after)
+}
+
+func loadAccessibleRecurrence(c *gin.Context) (*models.TaskRecurrence, bool) {
+	principal := middleware.GetPrincipal(c)
+
+	var recurrence models.TaskRecurrence
+	if err := database.DB.First(&recurrence, c.Param("id")).Error; err != nil ||
+		!principal.InOrganization(recurrence.OrganizationID) ||
+		(!principal.Can(models.PermTaskViewAll) && !principal.CanSeeTeam(recurrence.TeamID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring task not found"})
+		return nil, false
+	}
+	return &recurrence, true
+}
+
+func GetTaskRecurrences(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusOK, []models.TaskRecurrence{})
+		return
+	}
+
+	db := database.DB.Where("organization_id = ?", *principal.OrganizationID)
+	if teamID := c.Query("team_id"); teamID != "" {
+		db = db.Where("team_id = ?", teamID)
+	}
+	if !principal.Can(models.PermTaskViewAll) {
+		db = db.Where("team_id IN ?", append([]uint{0}, principal.VisibleTeamIDs...))
+	}
+
+	var recurrences []models.TaskRecurrence
+	if err := db.Order("created_at desc").Find(&recurrences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, recurrences)
+}
+
+func CreateTaskRecurrence(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input struct {
+		Title       string              `json:"title" binding:"required"`
+		Description string              `json:"description"`
+		Priority    models.TaskPriority `json:"priority"`
+		AssigneeID  *uint               `json:"assignee_id"`
+		TeamID      uint                `json:"team_id" binding:"required"`
+		Rule        string              `json:"rule" binding:"required"`
+		StartsAt    *time.Time          `json:"starts_at"`
+		Until       *time.Time          `json:"until"`
+		DueInDays   int                 `json:"due_in_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, input.TeamID).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	if !canManageRecurrences(principal, team.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only leaders can create tasks"})
+		return
+	}
+
+	recurrence := models.TaskRecurrence{
+		OrganizationID: team.OrganizationID,
+		TeamID:         team.ID,
+		CreatorID:      principal.ID(),
+		Title:          input.Title,
+		Description:    input.Description,
+		Priority:       input.Priority,
+		AssigneeID:     input.AssigneeID,
+		Rule:           input.Rule,
+		Until:          input.Until,
+		DueInDays:      input.DueInDays,
+		Active:         true,
+	}
+	if input.StartsAt != nil {
+		recurrence.StartsAt = *input.StartsAt
+	}
+
+	rule, err := prepareRecurrence(&recurrence)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scheduleRecurrence(&recurrence, rule)
+
+	if err := database.DB.Create(&recurrence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring task"})
+		return
+	}
+	c.JSON(http.StatusCreated, recurrence)
+}
+
+func UpdateTaskRecurrence(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	recurrence, ok := loadAccessibleRecurrence(c)
+	if !ok {
+		return
+	}
+	if !canManageRecurrences(principal, recurrence.TeamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	// Fields missing from the body keep their values; explicit nulls clear them.
+	changes := *recurrence
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recurrence.Title = changes.Title
+	recurrence.Description = changes.Description
+	recurrence.Priority = changes.Priority
+	recurrence.AssigneeID = changes.AssigneeID
+	recurrence.Rule = changes.Rule
+	recurrence.StartsAt = changes.StartsAt
+	recurrence.Until = changes.Until
+	recurrence.DueInDays = changes.DueInDays
+	recurrence.Active = changes.Active
+
+	rule, err := prepareRecurrence(recurrence)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scheduleRecurrence(recurrence, rule)
+
+	if err := database.DB.Save(recurrence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recurring task"})
+		return
+	}
+	c.JSON(http.StatusOK, recurrence)
+}
+
+func DeleteTaskRecurrence(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	recurrence, ok := loadAccessibleRecurrence(c)
+	if !ok {
+		return
+	}
+	if !canManageRecurrences(principal, recurrence.TeamID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := database.DB.Delete(recurrence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recurring task"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring task deleted; created tasks are kept"})
+}
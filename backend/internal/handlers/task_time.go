@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxLoggedMinutes = 24 * 60
+
+type TimeLogInput struct {
+	Minutes *int    `json:"minutes"`
+	Date    *string `json:"date"`
+	Note    *string `json:"note"`
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseDay(value string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return day, errors.New("Invalid date, expected YYYY-MM-DD")
+	}
+	return day, nil
+}
+
+// finishedTimeLogs excludes timers that are still running.
+func finishedTimeLogs(db *gorm.DB) *gorm.DB {
+	return db.Where("task_time_logs.started_at IS NULL OR task_time_logs.stopped_at IS NOT NULL")
+}
+
+func canManageTimeLog(principal *middleware.Principal, task *models.Task, log *models.TaskTimeLog) bool {
+	return log.UserID == principal.ID() || principal.ManagesTeam(task.TeamID) || principal.Can(models.PermTaskDelete)
+}
+
+// stopTimer closes a running timer, rounding the elapsed time up to a minute.
+func stopTimer(db *gorm.DB, log *models.TaskTimeLog) error {
+	now := time.Now()
+	minutes := int(math.Ceil(now.Sub(*log.StartedAt).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes > maxLoggedMinutes {
+		minutes = maxLoggedMinutes
+	}
+	log.StoppedAt = &now
+	log.Minutes = minutes
+	return db.Model(log).Updates(map[string]interface{}{"stopped_at": now, "minutes": minutes}).Error
+}
+
+func runningTimer(db *gorm.DB, userID uint) (*models.TaskTimeLog, error) {
+	var log models.TaskTimeLog
+	err := db.Where("user_id = ? AND started_at IS NOT NULL AND stopped_at IS NULL", userID).First(&log).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+func GetTaskTimeLogs(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var logs []models.TaskTimeLog
+	if err := database.DB.Preload("User").
+		Where("task_id = ?", task.ID).
+		Order("date desc, id desc").
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time logs"})
+		return
+	}
+
+	total := 0
+	for _, log := range logs {
+		total += log.Minutes
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"logs":             logs,
+		"total_minutes":    total,
+		"estimate_minutes": task.EstimateMinutes,
+	})
+}
+
+func CreateTaskTimeLog(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var input TimeLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Minutes == nil || *input.Minutes < 1 || *input.Minutes > maxLoggedMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Minutes must be between 1 and 1440"})
+		return
+	}
+
+	log := models.TaskTimeLog{
+		TaskID:  task.ID,
+		UserID:  principal.ID(),
+		Minutes: *input.Minutes,
+		Date:    startOfDay(time.Now()),
+	}
+	if input.Date != nil {
+		day, err := parseDay(*input.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Date = day
+	}
+	if input.Note != nil {
+		log.Note = strings.TrimSpace(*input.Note)
+	}
+
+	if err := database.DB.Create(&log).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log time"})
+		return
+	}
+	c.JSON(http.StatusCreated, log)
+}
+
+func UpdateTaskTimeLog(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var log models.TaskTimeLog
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("logId"), task.ID).First(&log).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time log not found"})
+		return
+	}
+	if !canManageTimeLog(principal, task, &log) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+	if log.StartedAt != nil && log.StoppedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stop the timer before editing this entry"})
+		return
+	}
+
+	var input TimeLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if input.Minutes != nil {
+		if *input.Minutes < 1 || *input.Minutes > maxLoggedMinutes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Minutes must be between 1 and 1440"})
+			return
+		}
+		updates["minutes"] = *input.Minutes
+	}
+	if input.Date != nil {
+		day, err := parseDay(*input.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["date"] = day
+	}
+	if input.Note != nil {
+		updates["note"] = strings.TrimSpace(*input.Note)
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(&log).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time log"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, log)
+}
+
+func DeleteTaskTimeLog(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var log models.TaskTimeLog
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("logId"), task.ID).First(&log).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time log not found"})
+		return
+	}
+	if !canManageTimeLog(principal, task, &log) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := database.DB.Delete(&log).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete time log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Time log deleted"})
+}
+
+// StartTaskTimer starts a timer on the task. A timer running on another task
+// is stopped first, so a user has at most one running timer.
+func StartTaskTimer(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	var note string
+	var input TimeLogInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if input.Note != nil {
+			note = strings.TrimSpace(*input.Note)
+		}
+	}
+
+	now := time.Now()
+	log := models.TaskTimeLog{
+		TaskID:    task.ID,
+		UserID:    principal.ID(),
+		Date:      startOfDay(now),
+		Note:      note,
+		StartedAt: &now,
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		running, err := runningTimer(tx, principal.ID())
+		if err != nil {
+			return err
+		}
+		if running != nil {
+			if running.TaskID == task.ID {
+				log = *running
+				return nil
+			}
+			if err := stopTimer(tx, running); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&log).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start timer"})
+		return
+	}
+	c.JSON(http.StatusOK, log)
+}
+
+func StopTaskTimer(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	running, err := runningTimer(database.DB, principal.ID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop timer"})
+		return
+	}
+	if running == nil || running.TaskID != task.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No timer is running on this task"})
+		return
+	}
+
+	if err := stopTimer(database.DB, running); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop timer"})
+		return
+	}
+	c.JSON(http.StatusOK, running)
+}
+
+// GetRunningTimer returns the caller's running timer, or null.
+func GetRunningTimer(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	running, err := runningTimer(database.DB, principal.ID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timer"})
+		return
+	}
+	c.JSON(http.StatusOK, running)
+}
+
+type TimeReportRow struct {
+	TeamID   uint   `json:"team_id"`
+	TeamName string `json:"team_name"`
+	UserID   uint   `json:"user_id"`
+	FullName string `json:"full_name"`
+	Minutes  int    `json:"minutes"`
+}
+
+type TimeReportTeam struct {
+	TeamID          uint   `json:"team_id"`
+	TeamName        string `json:"team_name"`
+	LoggedMinutes   int    `json:"logged_minutes"`
+	EstimateMinutes int    `json:"estimate_minutes"`
+}
+
+// GetTimeReport sums logged time per team and user between from and to
+// (inclusive, default: the last 30 days). Users with task.view_all see the
+// whole organization, team leaders and deputies their teams and sub-teams,
+// everyone else only their own time. Estimates are summed over the tasks
+// that have time logged in the range.
+func GetTimeReport(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are not in an organization"})
+		return
+	}
+	orgID := *principal.OrganizationID
+
+	to := startOfDay(time.Now())
+	if value := c.Query("to"); value != "" {
+		day, err := parseDay(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to = day
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := c.Query("from"); value != "" {
+		day, err := parseDay(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		from = day
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	var teamFilter, userFilter uint64
+	var err error
+	if value := c.Query("team_id"); value != "" {
+		if teamFilter, err = strconv.ParseUint(value, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team_id"})
+			return
+		}
+	}
+	if value := c.Query("user_id"); value != "" {
+		if value == "me" {
+			userFilter = uint64(principal.ID())
+		} else if userFilter, err = strconv.ParseUint(value, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+	}
+
+	var managedTeamIDs []uint
+	if !principal.Can(models.PermTaskViewAll) {
+		tree, err := loadTeamTree(database.DB, orgID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load teams"})
+			return
+		}
+		for teamID := range principal.TeamRoles {
+			if principal.ManagesTeam(teamID) {
+				managedTeamIDs = append(managedTeamIDs, teamID)
+				managedTeamIDs = append(managedTeamIDs, tree.Descendants(teamID)...)
+			}
+		}
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = finishedTimeLogs(db.Joins("JOIN tasks ON tasks.id = task_time_logs.task_id")).
+			Where("tasks.organization_id = ?", orgID).
+			Where("task_time_logs.date >= ? AND task_time_logs.date < ?", from, to.AddDate(0, 0, 1))
+		if teamFilter != 0 {
+			db = db.Where("tasks.team_id = ?", teamFilter)
+		}
+		if userFilter != 0 {
+			db = db.Where("task_time_logs.user_id = ?", userFilter)
+		}
+		if !principal.Can(models.PermTaskViewAll) {
+			if len(managedTeamIDs) > 0 {
+				db = db.Where("(tasks.team_id IN ? OR task_time_logs.user_id = ?)", managedTeamIDs, principal.ID())
+			} else {
+				db = db.Where("task_time_logs.user_id = ?", principal.ID())
+			}
+		}
+		return db
+	}
+
+	rows := []TimeReportRow{}
+	if err := scope(database.DB.Model(&models.TaskTimeLog{})).
+		Select("tasks.team_id, teams.name AS team_name, task_time_logs.user_id, users.full_name, SUM(task_time_logs.minutes) AS minutes").
+		Joins("LEFT JOIN teams ON teams.id = tasks.team_id").
+		Joins("LEFT JOIN users ON users.id = task_time_logs.user_id").
+		Group("tasks.team_id, teams.name, task_time_logs.user_id, users.full_name").
+		Order("teams.name asc, users.full_name asc").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	taskIDs := scope(database.DB.Model(&models.TaskTimeLog{})).Select("DISTINCT task_time_logs.task_id")
+	var estimates []struct {
+		TeamID   uint
+		Estimate int
+	}
+	if err := database.DB.Model(&models.Task{}).
+		Select("team_id, COALESCE(SUM(estimate_minutes), 0) AS estimate").
+		Where("id IN (?)", taskIDs).
+		Group("team_id").
+		Scan(&estimates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+	estimateByTeam := make(map[uint]int, len(estimates))
+	for _, e := range estimates {
+		estimateByTeam[e.TeamID] = e.Estimate
+	}
+
+	teams := []TimeReportTeam{}
+	index := make(map[uint]int)
+	total := 0
+	for _, row := range rows {
+		i, ok := index[row.TeamID]
+		if !ok {
+			i = len(teams)
+			index[row.TeamID] = i
+			teams = append(teams, TimeReportTeam{TeamID: row.TeamID, TeamName: row.TeamName, EstimateMinutes: estimateByTeam[row.TeamID]})
+		}
+		teams[i].LoggedMinutes += row.Minutes
+		total += row.Minutes
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"total_minutes": total,
+		"teams":         teams,
+		"rows":          rows,
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validateTaskParent checks that parentID may become the parent of task.
+// Zero detaches the task from its parent.
+func validateTaskParent(db *gorm.DB, task *models.Task, parentID uint) (*uint, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+	if parentID == task.ID {
+		return nil, errors.New("Task cannot be its own parent")
+	}
+
+	var parent models.Task
+	if err := db.Where("id = ? AND organization_id = ?", parentID, task.OrganizationID).First(&parent).Error; err != nil {
+		return nil, errors.New("Parent task not found")
+	}
+	if parent.TeamID != task.TeamID {
+		return nil, errors.New("Parent task must be in the same team")
+	}
+
+	if task.ID != 0 {
+		descendants, err := taskDescendantIDs(db, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range descendants {
+			if id == parent.ID {
+				return nil, errors.New("Cannot move a task under its own subtask")
+			}
+		}
+	}
+	return &parent.ID, nil
+}
+
+func taskDescendantIDs(db *gorm.DB, rootID uint) ([]uint, error) {
+	var result []uint
+	frontier := []uint{rootID}
+	for len(frontier) > 0 {
+		var children []uint
+		if err := db.Model(&models.Task{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, children...)
+		frontier = children
+	}
+	return result, nil
+}
+
+// loadSubtrees fills Subtasks and Progress of the given tasks, recursively.
+func loadSubtrees(db *gorm.DB, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byParent := make(map[uint][]models.Task)
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	allIDs := append([]uint{}, ids...)
+	frontier := ids
+	for len(frontier) > 0 {
+		var children []models.Task
+		if err := db.Preload("Assignee").Preload("Creator").
+			Where("parent_id IN ?", frontier).
+			Order("created_at asc, id asc").
+			Find(&children).Error; err != nil {
+			return err
+		}
+		frontier = frontier[:0:0]
+		for _, child := range children {
+			byParent[*child.ParentID] = append(byParent[*child.ParentID], child)
+			frontier = append(frontier, child.ID)
+			allIDs = append(allIDs, child.ID)
+		}
+	}
+
+	var counts []struct {
+		TaskID uint
+		Total  int
+		Done   int
+	}
+	if err := db.Model(&models.TaskChecklistItem{}).
+		Select("task_id, COUNT(*) AS total, SUM(CASE WHEN done THEN 1 ELSE 0 END) AS done").
+		Where("task_id IN ?", allIDs).
+		Group("task_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+	checklist := make(map[uint][2]int, len(counts))
+	for _, row := range counts {
+		checklist[row.TaskID] = [2]int{row.Done, row.Total}
+	}
+
+	// build attaches children and returns the done/total work units of the
+	// subtree: every checklist item and every subtask counts as one unit.
+	var build func(task *models.Task) (int, int)
+	build = func(task *models.Task) (int, int) {
+		done, total := checklist[task.ID][0], checklist[task.ID][1]
+		task.Subtasks = byParent[task.ID]
+		for i := range task.Subtasks {
+			child := &task.Subtasks[i]
+			childDone, childTotal := build(child)
+			done += childDone
+			total += childTotal + 1
+			if child.Status == models.StatusDone {
+				done++
+			}
+		}
+
+		progress := 0
+		switch {
+		case total > 0:
+			progress = done * 100 / total
+		case task.Status == models.StatusDone:
+			progress = 100
+		}
+		task.Progress = &progress
+		return done, total
+	}
+	for i := range tasks {
+		build(&tasks[i])
+	}
+	return nil
+}
+
+// openBlockers returns the tasks blocking taskID that are not done yet.
+func openBlockers(db *gorm.DB, taskID uint) ([]models.Task, error) {
+	var blockers []models.Task
+	err := db.Model(&models.Task{}).
+		Joins("JOIN task_dependencies ON task_dependencies.blocked_by_id = tasks.id").
+		Where("task_dependencies.task_id = ? AND tasks.status != ?", taskID, models.StatusDone).
+		Find(&blockers).Error
+	return blockers, err
+}
+
+func describeBlockers(blockers []models.Task) string {
+	names := make([]string, len(blockers))
+	for i, blocker := range blockers {
+		names[i] = fmt.Sprintf("#%d %s", blocker.ID, blocker.Title)
+	}
+	return "Task is blocked by: " + strings.Join(names, ", ")
+}
+
+// GetTask returns a single task with its subtree and completion percentage.
+func GetTask(c *gin.Context) {
+	task, ok := loadAccessibleTask(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Preload("Assignee").Preload("Creator").First(task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+	tasks := []models.Task{*task}
+	if err := loadSubtrees(database.DB, tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subtasks"})
+		return
+	}
+	c.JSON(http.StatusOK, tasks[0])
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// setTeamMember adds the user to the team or changes their role in it.
+func setTeamMember(db *gorm.DB, team *models.Team, userID uint, role models.TeamRole) error {
+	var teamMember models.TeamMember
+	err := db.Where("team_id = ? AND user_id = ?", team.ID, userID).First(&teamMember).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		if err := db.Model(&teamMember).Update("role", role).Error; err != nil {
+			return err
+		}
+	} else {
+		teamMember = models.TeamMember{TeamID: team.ID, UserID: userID, Role: role}
+		if err := db.Create(&teamMember).Error; err != nil {
+			return err
+		}
+	}
+	return syncPrimaryTeam(db, userID, team.OrganizationID)
+}
+
+func removeTeamMember(db *gorm.DB, team *models.Team, userID uint) error {
+	if err := db.Where("team_id = ? AND user_id = ?", team.ID, userID).Delete(&models.TeamMember{}).Error; err != nil {
+		return err
+	}
+	return syncPrimaryTeam(db, userID, team.OrganizationID)
+}
+
+// syncPrimaryTeam keeps membership.team_id pointing at one of the user's teams
+// in the organization, preferring the current one.
+func syncPrimaryTeam(db *gorm.DB, userID, orgID uint) error {
+	membership, err := findMembership(db, userID, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return middleware.BumpPrincipalVersion(db, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	var teamIDs []uint
+	if err := db.Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id").
+		Where("team_members.user_id = ? AND teams.organization_id = ?", userID, orgID).
+		Order("team_members.created_at asc").
+		Pluck("team_members.team_id", &teamIDs).Error; err != nil {
+		return err
+	}
+
+	var primary *uint
+	for i, id := range teamIDs {
+		if membership.TeamID != nil && *membership.TeamID == id {
+			primary = &teamIDs[i]
+			break
+		}
+	}
+	if primary == nil && len(teamIDs) > 0 {
+		primary = &teamIDs[0]
+	}
+
+	return updateMembership(db, &membership, map[string]interface{}{"team_id": primary})
+}
+
+// addToTeamAsPrimary adds the user to the team, keeping their role if they are
+// already in it, and makes it their primary team.
+func addToTeamAsPrimary(db *gorm.DB, team *models.Team, membership *models.Membership) error {
+	var count int64
+	db.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", team.ID, membership.UserID).Count(&count)
+	if count == 0 {
+		if err := db.Create(&models.TeamMember{TeamID: team.ID, UserID: membership.UserID, Role: models.TeamRoleMember}).Error; err != nil {
+			return err
+		}
+	}
+	return updateMembership(db, membership, map[string]interface{}{"team_id": team.ID})
+}
+
+func userTeams(db *gorm.DB, userID, orgID uint) []models.UserTeamResponse {
+	var rows []models.UserTeamResponse
+	db.Model(&models.TeamMember{}).
+		Select("team_members.team_id, teams.name, team_members.role AS team_role").
+		Joins("JOIN teams ON teams.id = team_members.team_id").
+		Where("team_members.user_id = ? AND teams.organization_id = ?", userID, orgID).
+		Order("teams.name asc").
+		Scan(&rows)
+	return rows
+}
+
+type TeamMemberRoleInput struct {
+	Role models.TeamRole `json:"role" binding:"required"`
+}
+
+func UpdateTeamMemberRole(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	teamID := c.Param("id")
+	targetUserID := c.Param("userId")
+
+	var input TeamMemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Role != models.TeamRoleMember && input.Role != models.TeamRoleDeputy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Allowed: member, deputy. Use the leader endpoint to change the leader"})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, teamID).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	if !principal.ManagesTeam(team.ID) && !canManageTeam(principal, &team, models.PermTeamManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var teamMember models.TeamMember
+	if err := database.DB.Where("team_id = ? AND user_id = ?", team.ID, targetUserID).First(&teamMember).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not in this team"})
+		return
+	}
+	if teamMember.Role == models.TeamRoleLeader {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change the leader's role. Change leader first."})
+		return
+	}
+
+	if err := setTeamMember(database.DB, &team, teamMember.UserID, input.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+		return
+	}
+	publishMemberEvent(events.TeamMemberUpdated, team.OrganizationID, teamMember.UserID, &team.ID, input.Role)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member role updated", "role": input.Role})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func loadTeamTree(db *gorm.DB, orgID uint) (*models.TeamTree, error) {
+	var teams []models.Team
+	if err := db.Select("id", "parent_id").Where("organization_id = ?", orgID).Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	return models.NewTeamTree(teams), nil
+}
+
+// validateTeamParent checks that parentID may become the parent of team.
+// Zero means the team moves to the top level. Run it in the transaction that
+// moves the team, so a concurrent move cannot slip a cycle past the check.
+func validateTeamParent(db *gorm.DB, team *models.Team, parentID uint) (*uint, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+	if parentID == team.ID {
+		return nil, errors.New("Team cannot be its own parent")
+	}
+
+	var parent models.Team
+	if err := db.Where("id = ? AND organization_id = ?", parentID, team.OrganizationID).First(&parent).Error; err != nil {
+		return nil, errors.New("Parent team must be from the same organization")
+	}
+
+	tree, err := loadTeamTree(db, team.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if tree.IsDescendant(parent.ID, team.ID) {
+		return nil, errors.New("Cannot move a team under its own sub-team")
+	}
+	return &parent.ID, nil
+}
+
+func sameTeamRef(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func GetOrganizationTree(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var org models.Organization
+	if err := database.DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if !isMember(principal.ID(), org.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var teams []models.Team
+	if err := database.DB.Where("organization_id = ?", org.ID).Order("name asc").Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+
+	var counts []struct {
+		TeamID uint
+		Count  int64
+	}
+	database.DB.Model(&models.TeamMember{}).
+		Select("team_id, COUNT(*) AS count").
+		Where("team_id IN (SELECT id FROM teams WHERE organization_id = ?)", org.ID).
+		Group("team_id").
+		Scan(&counts)
+	membersCount := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		membersCount[row.TeamID] = row.Count
+	}
+
+	byID := make(map[uint]models.Team, len(teams))
+	for _, team := range teams {
+		byID[team.ID] = team
+	}
+	tree := models.NewTeamTree(teams)
+
+	var build func(teamID uint) models.TeamTreeNode
+	build = func(teamID uint) models.TeamTreeNode {
+		team := byID[teamID]
+		node := models.TeamTreeNode{
+			ID:           team.ID,
+			Name:         team.Name,
+			AvatarURL:    team.AvatarURL,
+			LeaderID:     team.LeaderID,
+			MembersCount: membersCount[team.ID],
+			Children:     []models.TeamTreeNode{},
+		}
+		for _, childID := range tree.Children(team.ID) {
+			node.Children = append(node.Children, build(childID))
+		}
+		return node
+	}
+
+	roots := []models.TeamTreeNode{}
+	for _, team := range teams {
+		if team.ParentID == nil || byID[*team.ParentID].ID == 0 {
+			roots = append(roots, build(team.ID))
+		}
+	}
+
+	c.JSON(http.StatusOK, roots)
+}
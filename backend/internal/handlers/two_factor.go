@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/ratelimit"
+	"corp-portal/internal/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer           = "Corp Portal"
+	loginChallengeTTL    = 5 * time.Minute
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// normalizeRecoveryCode lets users type recovery codes in any case, with or
+// without the dash and spaces.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the
+// new ones in the xxxxx-xxxxx form shown to the user.
+func generateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code.
+// Either can only be used once.
+func checkSecondFactor(db *gorm.DB, user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := utils.MatchTOTP(user.TOTPSecret, strings.ReplaceAll(code, " ", ""), time.Now()); ok {
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.RowsAffected > 0, result.Error
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// twoFactorRequired reports whether any organization the user administers
+// requires 2FA.
+func twoFactorRequired(userID uint) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.Membership{}).
+		Joins("JOIN organizations ON organizations.id = memberships.organization_id").
+		Where("memberships.user_id = ? AND memberships.role >= ? AND organizations.require_two_factor = ?", userID, models.RoleAdmin, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func GetTwoFactorStatus(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var user models.User
+	if err := database.DB.First(&user, principal.ID()).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var codesLeft int64
+	database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&codesLeft)
+
+	required, err := twoFactorRequired(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":             user.TOTPEnabledAt != nil,
+		"enabled_at":          user.TOTPEnabledAt,
+		"recovery_codes_left": codesLeft,
+		"required":            required,
+	})
+}
+
+// EnrollTwoFactor starts enrollment with a new secret. 2FA is only enabled
+// once VerifyTwoFactor confirms a code from the authenticator app.
+func EnrollTwoFactor(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var user models.User
+	if err := database.DB.First(&user, principal.ID()).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := database.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// VerifyTwoFactor enables 2FA and returns the recovery codes. They are only
+// shown this once.
+func VerifyTwoFactor(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, principal.ID()).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor enrollment first"})
+		return
+	}
+
+	step, ok := utils.MatchTOTP(user.TOTPSecret, strings.ReplaceAll(strings.TrimSpace(input.Code), " ", ""), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		if codes, err = generateRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		return middleware.BumpPrincipalVersion(tx, user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func DisableTwoFactor(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, principal.ID()).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	required, err := twoFactorRequired(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your organization requires two-factor authentication"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	ok, err := checkSecondFactor(database.DB, &user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return middleware.BumpPrincipalVersion(tx, user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, principal.ID()).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	var codes []string
+	var ok bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if ok, err = checkSecondFactor(tx, &user, input.Code); err != nil || !ok {
+			return err
+		}
+		codes, err = generateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginTwoFactor answers the challenge returned by a first-factor login with
+// a TOTP or recovery code. A challenge allows a few attempts before it has to
+// be started over.
+func LoginTwoFactor(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var challenge models.UserToken
+	err := database.DB.Where("token_hash = ? AND purpose = ?", utils.HashToken(input.ChallengeToken), models.TokenLoginChallenge).
+		First(&challenge).Error
+	if err != nil || challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	// Count the attempt before checking the code so parallel guesses cannot
+	// exceed the limit.
+	result := database.DB.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", challenge.ID, maxChallengeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, challenge.UserID).Error; err != nil || user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	if lock := loginLockout.Locked(loginKey(user.Email)); lock > 0 {
+		ratelimit.TooManyRequests(c, lock, "Too many failed login attempts, try again later")
+		return
+	}
+
+	ok, err := checkSecondFactor(database.DB, &user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		recordLoginFailure(c, user.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if _, err := consumeUserToken(database.DB, input.ChallengeToken, models.TokenLoginChallenge); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	startSession(c, user)
+}
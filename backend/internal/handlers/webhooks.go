@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"corp-portal/internal/utils"
+	"corp-portal/internal/webhooks"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxWebhooksPerOrg       = 20
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+	webhookSecretPrefix     = "whsec_"
+)
+
+type CreateWebhookInput struct {
+	URL         string   `json:"url" binding:"required,max=2048"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=255"`
+	Active      *bool    `json:"active"`
+}
+
+// UpdateWebhookInput leaves nil fields unchanged.
+type UpdateWebhookInput struct {
+	URL         *string  `json:"url" binding:"omitempty,max=2048"`
+	Events      []string `json:"events" binding:"omitempty,min=1"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Active      *bool    `json:"active"`
+}
+
+func buildWebhookResponse(hook *models.Webhook) models.WebhookResponse {
+	response := models.WebhookResponse{
+		ID:          hook.ID,
+		URL:         hook.URL,
+		Description: hook.Description,
+		Active:      hook.Active,
+		Events:      make([]string, len(hook.Events)),
+		CreatedByID: hook.CreatedByID,
+		CreatedAt:   hook.CreatedAt,
+		UpdatedAt:   hook.UpdatedAt,
+	}
+	for i, s := range hook.Events {
+		response.Events[i] = s.EventType
+	}
+	return response
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.User == nil
+}
+
+// webhookSubscriptions checks the event types and returns them in the
+// order of events.Types, without duplicates.
+func webhookSubscriptions(eventTypes []string) ([]models.WebhookSubscription, string) {
+	seen := map[string]bool{}
+	for _, eventType := range eventTypes {
+		known := false
+		for _, t := range events.Types {
+			if t == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, "Unknown event type: " + eventType
+		}
+		seen[eventType] = true
+	}
+
+	var subscriptions []models.WebhookSubscription
+	for _, t := range events.Types {
+		if seen[t] {
+			subscriptions = append(subscriptions, models.WebhookSubscription{EventType: t})
+		}
+	}
+	return subscriptions, ""
+}
+
+// missingViewAll returns the permission the principal lacks to receive one
+// of the event types. Webhooks get events of every team, so team-scoped
+// types need the matching view-all permission.
+func missingViewAll(principal *middleware.Principal, subscriptions []models.WebhookSubscription) (string, models.Permission) {
+	for _, s := range subscriptions {
+		if perm, ok := events.TeamScopedTypes[s.EventType]; ok && !principal.Can(perm) {
+			return s.EventType, perm
+		}
+	}
+	return "", ""
+}
+
+// loadOrgWebhook finds the webhook in the caller's organization or responds
+// with 404.
+func loadOrgWebhook(c *gin.Context, principal *middleware.Principal) (*models.Webhook, bool) {
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	var hook models.Webhook
+	if err := database.DB.Preload("Events").
+		Where("id = ? AND organization_id = ?", c.Param("id"), *principal.OrganizationID).
+		First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return &hook, true
+}
+
+func GetWebhookEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, events.Types)
+}
+
+func GetWebhooks(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusOK, []models.WebhookResponse{})
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := database.DB.Preload("Events").
+		Where("organization_id = ?", *principal.OrganizationID).
+		Order("created_at desc").
+		Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	response := make([]models.WebhookResponse, len(hooks))
+	for i := range hooks {
+		response[i] = buildWebhookResponse(&hooks[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWebhook returns the signing secret once; it cannot be read back.
+func CreateWebhook(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if principal.OrganizationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be in an organization"})
+		return
+	}
+	if !validWebhookURL(input.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL must be an absolute http or https URL"})
+		return
+	}
+	subscriptions, msg := webhookSubscriptions(input.Events)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if eventType, perm := missingViewAll(principal, subscriptions); perm != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Subscribing to " + eventType + " requires the " + string(perm) + " permission"})
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.Webhook{}).Where("organization_id = ?", *principal.OrganizationID).Count(&count)
+	if count >= maxWebhooksPerOrg {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many webhooks, delete unused ones first"})
+		return
+	}
+
+	secret, _, err := utils.GenerateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	hook := models.Webhook{
+		OrganizationID: *principal.OrganizationID,
+		URL:            input.URL,
+		Secret:         webhookSecretPrefix + secret,
+		Description:    input.Description,
+		Active:         input.Active == nil || *input.Active,
+		CreatedByID:    principal.ID(),
+		Events:         subscriptions,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&hook).Error; err != nil {
+			return err
+		}
+		recordAudit(c, tx, hook.OrganizationID, models.AuditWebhookCreated, "webhook", hook.ID, nil,
+			gin.H{"url": hook.URL, "events": input.Events, "active": hook.Active})
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"secret":  hook.Secret,
+		"webhook": buildWebhookResponse(&hook),
+	})
+}
+
+func UpdateWebhook(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var input UpdateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hook, ok := loadOrgWebhook(c, principal)
+	if !ok {
+		return
+	}
+
+	before := gin.H{}
+	after := gin.H{}
+	updates := map[string]interface{}{}
+	if input.URL != nil && *input.URL != hook.URL {
+		if !validWebhookURL(*input.URL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "URL must be an absolute http or https URL"})
+			return
+		}
+		before["url"], after["url"] = hook.URL, *input.URL
+		updates["url"] = *input.URL
+	}
+	if input.Description != nil && *input.Description != hook.Description {
+		updates["description"] = *input.Description
+	}
+	if input.Active != nil && *input.Active != hook.Active {
+		before["active"], after["active"] = hook.Active, *input.Active
+		updates["active"] = *input.Active
+	}
+	var subscriptions []models.WebhookSubscription
+	if input.Events != nil {
+		var msg string
+		if subscriptions, msg = webhookSubscriptions(input.Events); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if eventType, perm := missingViewAll(principal, subscriptions); perm != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Subscribing to " + eventType + " requires the " + string(perm) + " permission"})
+			return
+		}
+		before["events"] = buildWebhookResponse(hook).Events
+		after["events"] = input.Events
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(hook).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if input.Events != nil {
+			if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookSubscription{}).Error; err != nil {
+				return err
+			}
+			for i := range subscriptions {
+				subscriptions[i].WebhookID = hook.ID
+			}
+			if err := tx.Create(&subscriptions).Error; err != nil {
+				return err
+			}
+			hook.Events = subscriptions
+		}
+		if len(after) > 0 {
+			recordAudit(c, tx, hook.OrganizationID, models.AuditWebhookUpdated, "webhook", hook.ID, before, after)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildWebhookResponse(hook))
+}
+
+func DeleteWebhook(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	hook, ok := loadOrgWebhook(c, principal)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(hook).Error; err != nil {
+			return err
+		}
+		recordAudit(c, tx, hook.OrganizationID, models.AuditWebhookDeleted, "webhook", hook.ID,
+			gin.H{"url": hook.URL, "events": buildWebhookResponse(hook).Events}, nil)
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// GetWebhookDeliveries lists a webhook's deliveries, newest first,
+// optionally filtered by status. Pages use limit and the cursor from
+// X-Next-Cursor.
+func GetWebhookDeliveries(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	hook, ok := loadOrgWebhook(c, principal)
+	if !ok {
+		return
+	}
+
+	db := database.DB.Where("webhook_id = ?", hook.ID)
+	if value := c.Query("status"); value != "" {
+		db = db.Where("status = ?", value)
+	}
+
+	limit := defaultDeliveryPageSize
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxDeliveryPageSize {
+			limit = maxDeliveryPageSize
+		}
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		db = db.Where("id < ?", cursor)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := db.Order("id desc").Limit(limit + 1).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		c.Header("X-Next-Cursor", strconv.FormatUint(uint64(deliveries[limit-1].ID), 10))
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDelivery queues the same event again as a new delivery. The
+// payload, and so the event id, is unchanged.
+func ReplayWebhookDelivery(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	hook, ok := loadOrgWebhook(c, principal)
+	if !ok {
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := database.DB.Where("id = ? AND webhook_id = ?", c.Param("deliveryId"), hook.ID).First(&original).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventID:   original.EventID,
+		EventType: original.EventType,
+		Payload:   original.Payload,
+	}
+	if err := webhooks.Queue(&delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue delivery"})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
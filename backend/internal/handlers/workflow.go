@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/middleware"
+	"corp-portal/internal/models"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var statusKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+// loadWorkflow returns the team's workflow, or the default one if the team has not defined its own.
+func loadWorkflow(db *gorm.DB, teamID uint) (models.Workflow, error) {
+	var statuses []models.TeamWorkflowStatus
+	if err := db.Where("team_id = ?", teamID).Order("position asc").Find(&statuses).Error; err != nil {
+		return models.Workflow{}, err
+	}
+	if len(statuses) == 0 {
+		return models.DefaultWorkflow(), nil
+	}
+
+	var transitions []models.TeamWorkflowTransition
+	if err := db.Where("team_id = ?", teamID).Order("id asc").Find(&transitions).Error; err != nil {
+		return models.Workflow{}, err
+	}
+
+	workflow := models.Workflow{
+		Statuses:    make([]models.WorkflowStatus, len(statuses)),
+		Transitions: make([]models.WorkflowTransition, len(transitions)),
+	}
+	for i, s := range statuses {
+		workflow.Statuses[i] = models.WorkflowStatus{Key: s.Key, Name: s.Name}
+	}
+	for i, t := range transitions {
+		workflow.Transitions[i] = models.WorkflowTransition{
+			From:   t.FromStatus,
+			To:     t.ToStatus,
+			Actors: models.SplitWorkflowActors(t.Actors),
+		}
+	}
+	return workflow, nil
+}
+
+func validateWorkflow(workflow *models.Workflow) error {
+	if len(workflow.Statuses) == 0 {
+		return fmt.Errorf("Workflow needs at least one status")
+	}
+
+	seen := make(map[models.TaskStatus]bool)
+	for i, s := range workflow.Statuses {
+		key := models.TaskStatus(strings.ToUpper(strings.TrimSpace(string(s.Key))))
+		if !statusKeyPattern.MatchString(string(key)) {
+			return fmt.Errorf("Invalid status key %q: use A-Z, 0-9 and _", s.Key)
+		}
+		if seen[key] {
+			return fmt.Errorf("Duplicate status %s", key)
+		}
+		seen[key] = true
+		workflow.Statuses[i].Key = key
+		if strings.TrimSpace(s.Name) == "" {
+			workflow.Statuses[i].Name = string(key)
+		}
+	}
+	// Overdue filters, digests, sprint rollover and blockers all treat DONE as finished.
+	if !seen[models.StatusDone] {
+		return fmt.Errorf("Workflow must keep the %s status", models.StatusDone)
+	}
+
+	pairs := make(map[string]bool)
+	for i, t := range workflow.Transitions {
+		from := models.TaskStatus(strings.ToUpper(string(t.From)))
+		to := models.TaskStatus(strings.ToUpper(string(t.To)))
+		if !seen[from] || !seen[to] {
+			return fmt.Errorf("Transition %s → %s uses an unknown status", t.From, t.To)
+		}
+		if from == to {
+			return fmt.Errorf("Transition %s → %s goes nowhere", from, to)
+		}
+		pair := string(from) + ">" + string(to)
+		if pairs[pair] {
+			return fmt.Errorf("Duplicate transition %s → %s", from, to)
+		}
+		pairs[pair] = true
+		if len(t.Actors) == 0 {
+			return fmt.Errorf("Transition %s → %s needs at least one actor", from, to)
+		}
+		for _, actor := range t.Actors {
+			if !models.IsValidWorkflowActor(actor) {
+				return fmt.Errorf("Unknown actor %q. Allowed: anyone, creator, assignee, leader", actor)
+			}
+		}
+		workflow.Transitions[i].From = from
+		workflow.Transitions[i].To = to
+	}
+	return nil
+}
+
+// checkTransition returns a user-facing reason if the principal may not move the task to the new status.
+func checkTransition(principal *middleware.Principal, workflow *models.Workflow, task *models.Task, to models.TaskStatus) string {
+	if !workflow.HasStatus(to) {
+		return fmt.Sprintf("Unknown status %s for this team", to)
+	}
+	transition, ok := workflow.Transition(task.Status, to)
+	if !ok {
+		return fmt.Sprintf("Transition %s → %s is not allowed", task.Status, to)
+	}
+
+	for _, actor := range transition.Actors {
+		switch actor {
+		case models.ActorAnyone:
+			return ""
+		case models.ActorCreator:
+			if task.CreatorID == principal.ID() {
+				return ""
+			}
+		case models.ActorAssignee:
+			if task.AssigneeID != nil && *task.AssigneeID == principal.ID() {
+				return ""
+			}
+		case models.ActorLeader:
+			if actsAsTaskLeader(principal, task) {
+				return ""
+			}
+		}
+	}
+
+	names := make([]string, len(transition.Actors))
+	for i, actor := range transition.Actors {
+		names[i] = string(actor)
+	}
+	return fmt.Sprintf("Only %s can move a task %s → %s", strings.Join(names, " or "), task.Status, to)
+}
+
+func GetTeamWorkflow(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("id")).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	workflow, err := loadWorkflow(database.DB, team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load workflow"})
+		return
+	}
+	c.JSON(http.StatusOK, workflow)
+}
+
+func UpdateTeamWorkflow(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("id")).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	if !principal.LeadsTeam(team.ID) && !canManageTeam(principal, &team, models.PermTeamUpdate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Team Leader or Admin can change the workflow"})
+		return
+	}
+
+	var workflow models.Workflow
+	if err := c.ShouldBindJSON(&workflow); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateWorkflow(&workflow); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var usedStatuses []models.TaskStatus
+	database.DB.Model(&models.Task{}).Where("team_id = ?", team.ID).Distinct().Pluck("status", &usedStatuses)
+	for _, status := range usedStatuses {
+		if !workflow.HasStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tasks still use status %s; move them before removing it", status)})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTeamWorkflow(tx, team.ID); err != nil {
+			return err
+		}
+		for i, s := range workflow.Statuses {
+			if err := tx.Create(&models.TeamWorkflowStatus{TeamID: team.ID, Key: s.Key, Name: s.Name, Position: i}).Error; err != nil {
+				return err
+			}
+		}
+		for _, t := range workflow.Transitions {
+			if err := tx.Create(&models.TeamWorkflowTransition{
+				TeamID:     team.ID,
+				FromStatus: t.From,
+				ToStatus:   t.To,
+				Actors:     models.JoinWorkflowActors(t.Actors),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save workflow"})
+		return
+	}
+
+	workflow.IsDefault = false
+	c.JSON(http.StatusOK, workflow)
+}
+
+// ResetTeamWorkflow drops the team's custom workflow so the default one applies again.
+func ResetTeamWorkflow(c *gin.Context) {
+	principal := middleware.GetPrincipal(c)
+
+	var team models.Team
+	if err := database.DB.First(&team, c.Param("id")).Error; err != nil || !principal.InOrganization(team.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	if !principal.LeadsTeam(team.ID) && !canManageTeam(principal, &team, models.PermTeamUpdate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Team Leader or Admin can change the workflow"})
+		return
+	}
+
+	workflow := models.DefaultWorkflow()
+	var usedStatuses []models.TaskStatus
+	database.DB.Model(&models.Task{}).Where("team_id = ?", team.ID).Distinct().Pluck("status", &usedStatuses)
+	for _, status := range usedStatuses {
+		if !workflow.HasStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tasks still use status %s; move them before resetting", status)})
+			return
+		}
+	}
+
+	if err := deleteTeamWorkflow(database.DB, team.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset workflow"})
+		return
+	}
+	c.JSON(http.StatusOK, workflow)
+}
+
+func deleteTeamWorkflow(db *gorm.DB, teamID uint) error {
+	if err := db.Where("team_id = ?", teamID).Delete(&models.TeamWorkflowTransition{}).Error; err != nil {
+		return err
+	}
+	return db.Where("team_id = ?", teamID).Delete(&models.TeamWorkflowStatus{}).Error
+}
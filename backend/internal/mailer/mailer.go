@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a rendered email with a plain text and an optional HTML body.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the handlers and background jobs. Setup
+// replaces it; until then mail is only logged.
+var Default Mailer = &Outbox{}
+
+var from = "Corp Portal <no-reply@localhost>"
+
+// Setup picks the mailer from MAIL_DRIVER:
+//   - smtp: SMTP_HOST, SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD
+//   - file: writes .eml files to MAIL_OUTBOX_DIR (outbox)
+//   - console (default): logs the text body
+func Setup() {
+	if value := os.Getenv("MAIL_FROM"); value != "" {
+		from = value
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		Default = &SMTP{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		Default = &Outbox{Dir: dir}
+	case "", "console":
+		Default = &Outbox{}
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+// Link builds an absolute link into the frontend from APP_URL.
+func Link(path string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// SendTemplate renders the named template in the locale and sends it through
+// the default mailer.
+func SendTemplate(to, locale, name string, data interface{}) error {
+	msg, err := Render(locale, name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+	return Default.Send(msg)
+}
+
+// Bytes encodes the message as a MIME document ready for SMTP or an .eml file.
+func (m Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	parts := []struct{ contentType, body string }{{"text/plain", m.Text}}
+	if m.HTML != "" {
+		parts = append(parts, struct{ contentType, body string }{"text/html", m.HTML})
+	}
+
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+	for _, part := range parts {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox keeps mail local for development and tests: with a Dir every message
+// is written there as an .eml file, otherwise it is logged.
+type Outbox struct {
+	Dir string
+}
+
+func (o *Outbox) Send(msg Message) error {
+	if o.Dir == "" {
+		log.Printf("Mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+		return nil
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return err
+	}
+	name := time.Now().Format("20060102-150405") + "-" + uuid.New().String() + ".eml"
+	return os.WriteFile(filepath.Join(o.Dir, name), body, 0644)
+}
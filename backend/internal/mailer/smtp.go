@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTP delivers mail through a relay, upgrading to TLS with STARTTLS when the
+// server offers it.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+func (s *SMTP) Send(msg Message) error {
+	if s.Host == "" {
+		return errors.New("SMTP_HOST is not set")
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return err
+	}
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, sender.Address, msg.To, body)
+}
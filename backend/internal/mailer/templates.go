@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Every template lives in templates/<locale>/ as <name>.txt, defining the
+// "subject" and "text" blocks, and <name>.html with the HTML body.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// DefaultLocale is used when a locale is empty or has no translation. It can
+// be set with MAIL_LOCALE.
+func DefaultLocale() string {
+	if locale := os.Getenv("MAIL_LOCALE"); locale != "" {
+		return locale
+	}
+	return "en"
+}
+
+// resolveLocale maps tags like "ru-RU" or an Accept-Language header to the
+// templates directory that has the named template, falling back to the
+// default locale.
+func resolveLocale(locale, name string) string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, "-_,;"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale != "" {
+		if _, err := fs.Stat(templateFS, path.Join("templates", locale, name+".txt")); err == nil {
+			return locale
+		}
+	}
+	return DefaultLocale()
+}
+
+// Render builds the message for the named template without recipients.
+func Render(locale, name string, data interface{}) (Message, error) {
+	dir := path.Join("templates", resolveLocale(locale, name))
+
+	text, err := texttemplate.ParseFS(templateFS, path.Join(dir, name+".txt"))
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return Message{}, err
+	}
+	msg := Message{Subject: strings.TrimSpace(subject.String()), Text: strings.TrimSpace(body.String()) + "\n"}
+
+	if html, err := htmltemplate.ParseFS(templateFS, path.Join(dir, name+".html")); err == nil {
+		var buf bytes.Buffer
+		if err := html.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
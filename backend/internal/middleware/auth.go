@@ -1,13 +1,49 @@
 package middleware
 
 import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
 	"corp-portal/internal/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+func SessionActive(userID, sessionID uint) bool {
+	var activeSessions int64
+	database.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Count(&activeSessions)
+	return activeSessions > 0
+}
+
+// AllowQueryToken lets clients that cannot set headers, such as the browser
+// EventSource, pass the access token as ?access_token=.
+func AllowQueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// twoFactorSetupRoutes stay reachable while the organization makes the user
+// enroll in 2FA.
+var twoFactorSetupRoutes = map[string]bool{
+	"/api/me":                       true,
+	"/api/profile/leave":            true,
+	"/api/logout":                   true,
+	"/api/logout/all":               true,
+	"/api/memberships":              true,
+	"/api/organizations/:id/switch": true,
+	"/api/2fa":                      true,
+	"/api/2fa/enroll":               true,
+	"/api/2fa/verify":               true,
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -22,15 +58,46 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		var userID uint
 		tokenString := parts[1]
-		claims, err := utils.ParseToken(tokenString)
+		if strings.HasPrefix(tokenString, APITokenPrefix) {
+			token, ok := authenticateAPIToken(c, tokenString)
+			if !ok {
+				return
+			}
+			userID = token.UserID
+			c.Set("apiTokenID", token.ID)
+		} else {
+			claims, err := utils.ParseToken(tokenString)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				return
+			}
+
+			if !SessionActive(claims.UserID, claims.SessionID) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				return
+			}
+			userID = claims.UserID
+			c.Set("sessionID", claims.SessionID)
+		}
+
+		principal, err := LoadPrincipal(userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+
+		if principal.TwoFactorSetupRequired && !twoFactorSetupRoutes[c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":                     "Your organization requires two-factor authentication",
+				"two_factor_setup_required": true,
+			})
 			return
 		}
 
-		c.Set("userID", claims.UserID)
-		c.Set("role", claims.Role)
+		c.Set("userID", userID)
+		c.Set("principal", principal)
 
 		c.Next()
 	}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger is gin's request log with the access_token query parameter masked,
+// since AllowQueryToken accepts bearer tokens there.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			redactQuery(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base
+	}
+	if query.Has("access_token") {
+		query.Set("access_token", "REDACTED")
+	}
+	return base + "?" + query.Encode()
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const principalCacheSize = 1024
+
+// Principal is the authenticated user as seen by the current request.
+// Role, team and permissions come from the membership of the active organization.
+type Principal struct {
+	User           models.User
+	Role           models.Role
+	OrganizationID *uint
+	TeamID         *uint
+	TeamIDs        []uint
+	TeamRoles      map[uint]models.TeamRole
+	// VisibleTeamIDs holds the principal's teams, their ancestors and the
+	// descendants of the teams it leads.
+	VisibleTeamIDs []uint
+	Permissions    map[models.Permission]bool
+	// TwoFactorSetupRequired is set when the organization requires 2FA for
+	// the principal's role and the user has not enabled it yet.
+	TwoFactorSetupRequired bool
+}
+
+func (p *Principal) ID() uint {
+	return p.User.ID
+}
+
+func (p *Principal) InOrganization(orgID uint) bool {
+	return p.OrganizationID != nil && *p.OrganizationID == orgID
+}
+
+func (p *Principal) InTeam(teamID uint) bool {
+	_, ok := p.TeamRoles[teamID]
+	return ok
+}
+
+// Can reports whether the principal holds the permission in its organization.
+func (p *Principal) Can(perm models.Permission) bool {
+	return p.Permissions[perm]
+}
+
+func (p *Principal) LeadsTeam(teamID uint) bool {
+	return p.TeamRoles[teamID] == models.TeamRoleLeader
+}
+
+// CanSeeTeam reports whether content targeted at the team reaches the principal.
+func (p *Principal) CanSeeTeam(teamID uint) bool {
+	for _, id := range p.VisibleTeamIDs {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+// ManagesTeam reports whether the principal is the leader or a deputy of the team.
+func (p *Principal) ManagesTeam(teamID uint) bool {
+	role := p.TeamRoles[teamID]
+	return role == models.TeamRoleLeader || role == models.TeamRoleDeputy
+}
+
+type cachedPrincipal struct {
+	version   uint
+	principal Principal
+}
+
+var principalCache = struct {
+	sync.RWMutex
+	entries map[uint]cachedPrincipal
+}{entries: make(map[uint]cachedPrincipal)}
+
+func LoadPrincipal(userID uint) (*Principal, error) {
+	var current struct {
+		Version uint
+	}
+	if err := database.DB.Model(&models.User{}).Select("version").Where("id = ?", userID).Take(&current).Error; err != nil {
+		return nil, err
+	}
+
+	principalCache.RLock()
+	entry, ok := principalCache.entries[userID]
+	principalCache.RUnlock()
+	if ok && entry.version == current.Version {
+		p := entry.principal
+		return &p, nil
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	p := Principal{
+		User:        user,
+		Role:        models.RoleUser,
+		TeamRoles:   make(map[uint]models.TeamRole),
+		Permissions: make(map[models.Permission]bool),
+	}
+
+	if user.OrganizationID != nil {
+		var membership models.Membership
+		err := database.DB.Where("user_id = ? AND organization_id = ?", user.ID, *user.OrganizationID).First(&membership).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			p.OrganizationID = &membership.OrganizationID
+			p.Role = membership.Role
+			p.TeamID = membership.TeamID
+
+			var teamMembers []models.TeamMember
+			if err := database.DB.Joins("JOIN teams ON teams.id = team_members.team_id").
+				Where("team_members.user_id = ? AND teams.organization_id = ?", user.ID, membership.OrganizationID).
+				Find(&teamMembers).Error; err != nil {
+				return nil, err
+			}
+			for _, tm := range teamMembers {
+				p.TeamIDs = append(p.TeamIDs, tm.TeamID)
+				p.TeamRoles[tm.TeamID] = tm.Role
+			}
+			if len(p.TeamIDs) > 0 {
+				if p.VisibleTeamIDs, err = visibleTeams(&p, membership.OrganizationID); err != nil {
+					return nil, err
+				}
+			}
+
+			if p.Permissions, err = resolvePermissions(&membership); err != nil {
+				return nil, err
+			}
+
+			if p.Role >= models.RoleAdmin && user.TOTPEnabledAt == nil {
+				var org models.Organization
+				if err := database.DB.Select("require_two_factor").First(&org, membership.OrganizationID).Error; err != nil {
+					return nil, err
+				}
+				p.TwoFactorSetupRequired = org.RequireTwoFactor
+			}
+		}
+	}
+
+	principalCache.Lock()
+	if len(principalCache.entries) >= principalCacheSize {
+		for id := range principalCache.entries {
+			delete(principalCache.entries, id)
+			break
+		}
+	}
+	principalCache.entries[userID] = cachedPrincipal{version: user.Version, principal: p}
+	principalCache.Unlock()
+
+	return &p, nil
+}
+
+func visibleTeams(p *Principal, orgID uint) ([]uint, error) {
+	var teams []models.Team
+	if err := database.DB.Select("id", "parent_id").Where("organization_id = ?", orgID).Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	tree := models.NewTeamTree(teams)
+
+	seen := make(map[uint]bool)
+	var ids []uint
+	add := func(list ...uint) {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, teamID := range p.TeamIDs {
+		add(teamID)
+		add(tree.Ancestors(teamID)...)
+		if p.LeadsTeam(teamID) {
+			add(tree.Descendants(teamID)...)
+		}
+	}
+	return ids, nil
+}
+
+func resolvePermissions(membership *models.Membership) (map[models.Permission]bool, error) {
+	permissions := make(map[models.Permission]bool)
+
+	granted := models.DefaultRolePermissions[membership.Role]
+	if membership.Role != models.RoleSuperAdmin {
+		query := database.DB.Preload("Permissions").Where("organization_id = ?", membership.OrganizationID)
+		if membership.OrgRoleID != nil {
+			query = query.Where("id = ?", *membership.OrgRoleID)
+		} else {
+			query = query.Where("is_system = ? AND base_role = ?", true, membership.Role)
+		}
+
+		var role models.OrgRole
+		err := query.First(&role).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			granted = make([]models.Permission, len(role.Permissions))
+			for i, rp := range role.Permissions {
+				granted[i] = rp.Permission
+			}
+		}
+	}
+
+	for _, perm := range granted {
+		permissions[perm] = true
+	}
+	return permissions, nil
+}
+
+// GetPrincipal returns the principal stored by AuthMiddleware.
+func GetPrincipal(c *gin.Context) *Principal {
+	return c.MustGet("principal").(*Principal)
+}
+
+// BumpPrincipalVersion invalidates cached principals of the given users.
+// Call it whenever a user's row, team membership or leadership changes.
+func BumpPrincipalVersion(db *gorm.DB, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return db.Model(&models.User{}).Where("id IN ?", userIDs).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// BumpOrganizationPrincipals invalidates cached principals of every member of the organization.
+func BumpOrganizationPrincipals(db *gorm.DB, orgID uint) error {
+	return db.Model(&models.User{}).Where("id IN (SELECT user_id FROM memberships WHERE organization_id = ?)", orgID).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// RequirePermission aborts the request unless the principal holds the permission.
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -29,6 +29,33 @@ func DefaultUploadConfig(uploadDir string) UploadConfig {
 	}
 }
 
+// AttachmentUploadConfig accepts images, PDFs, plain text and zip-based
+// office files sent in the "file" field.
+func AttachmentUploadConfig(uploadDir string) UploadConfig {
+	return UploadConfig{
+		MaxSize: 10 * 1024 * 1024, // 10MB
+		AllowedTypes: []string{
+			"image/jpeg", "image/png", "image/gif", "image/webp",
+			"application/pdf", "text/plain; charset=utf-8", "application/zip",
+		},
+		UploadDir: uploadDir,
+		FieldName: "file",
+	}
+}
+
+// fileExtensions maps the detected MIME type to the stored file extension.
+// The client's file name is never used, so a file is always served with the
+// type it was checked as.
+var fileExtensions = map[string]string{
+	"image/jpeg":                ".jpg",
+	"image/png":                 ".png",
+	"image/gif":                 ".gif",
+	"image/webp":                ".webp",
+	"application/pdf":           ".pdf",
+	"text/plain; charset=utf-8": ".txt",
+	"application/zip":           ".zip",
+}
+
 func Upload(config UploadConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != "POST" && c.Request.Method != "PUT" {
@@ -51,15 +78,15 @@ func Upload(config UploadConfig) gin.HandlerFunc {
 			return
 		}
 		buffer := make([]byte, 512)
-		_, err = file.Read(buffer)
-		if err != nil {
+		n, err := file.Read(buffer)
+		if err != nil && err != io.EOF {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать файл"})
 			c.Abort()
 			return
 		}
 		file.Seek(0, 0)
 
-		mimeType := http.DetectContentType(buffer)
+		mimeType := http.DetectContentType(buffer[:n])
 		allowed := false
 		for _, t := range config.AllowedTypes {
 			if t == mimeType {
@@ -67,34 +94,19 @@ func Upload(config UploadConfig) gin.HandlerFunc {
 				break
 			}
 		}
+		ext, known := fileExtensions[mimeType]
 
-		if !allowed {
+		if !allowed || !known {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": fmt.Sprintf("Недопустимый тип файла. Разрешены: %s", strings.Join(config.AllowedTypes, ", ")),
 			})
 			c.Abort()
 			return
 		}
-		ext := filepath.Ext(header.Filename)
-		if ext == "" {
-			switch mimeType {
-			case "image/jpeg":
-				ext = ".jpg"
-			case "image/png":
-				ext = ".png"
-			case "image/gif":
-				ext = ".gif"
-			case "image/webp":
-				ext = ".webp"
-			default:
-				ext = ".jpg"
-			}
-		}
-
 		fileName := fmt.Sprintf("%s_%d%s",
 			uuid.New().String()[:8],
 			time.Now().Unix(),
-			strings.ToLower(ext))
+			ext)
 
 		if err := os.MkdirAll(config.UploadDir, 0755); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать папку для загрузки"})
@@ -7,6 +7,7 @@ import (
 type UserProfileResponse struct {
 	ID             uint      `json:"id"`
 	Email          string    `json:"email"`
+	EmailVerified  bool      `json:"email_verified"`
 	FullName       string    `json:"full_name"`
 	AvatarURL      string    `json:"avatar_url"`
 	Bio            string    `json:"bio"`
@@ -19,6 +20,14 @@ type UserProfileResponse struct {
 
 	Organization *OrganizationResponse `json:"organization,omitempty"`
 	Team         *TeamResponse         `json:"team,omitempty"`
+	Teams        []UserTeamResponse    `json:"teams,omitempty"`
+	Permissions  []Permission          `json:"permissions,omitempty"`
+}
+
+type UserTeamResponse struct {
+	TeamID   uint     `json:"team_id"`
+	Name     string   `json:"name"`
+	TeamRole TeamRole `json:"team_role"`
 }
 
 type OrganizationResponse struct {
@@ -36,17 +45,28 @@ type TeamResponse struct {
 	Description    string              `json:"description"`
 	AvatarURL      string              `json:"avatar_url"`
 	OrganizationID uint                `json:"organization_id"`
+	ParentID       *uint               `json:"parent_id"`
 	LeaderID       *uint               `json:"leader_id"`
 	CreatedAt      time.Time           `json:"created_at"`
 	Leader         *UserSimpleResponse `json:"leader,omitempty"`
 }
 
+type TeamTreeNode struct {
+	ID           uint           `json:"id"`
+	Name         string         `json:"name"`
+	AvatarURL    string         `json:"avatar_url"`
+	LeaderID     *uint          `json:"leader_id"`
+	MembersCount int64          `json:"members_count"`
+	Children     []TeamTreeNode `json:"children"`
+}
+
 type UserSimpleResponse struct {
-	ID        uint   `json:"id"`
-	FullName  string `json:"full_name"`
-	Email     string `json:"email"`
-	AvatarURL string `json:"avatar_url"`
-	Role      Role   `json:"role"`
+	ID        uint     `json:"id"`
+	FullName  string   `json:"full_name"`
+	Email     string   `json:"email"`
+	AvatarURL string   `json:"avatar_url"`
+	Role      Role     `json:"role"`
+	TeamRole  TeamRole `json:"team_role,omitempty"`
 }
 
 type TeamProfileResponse struct {
@@ -55,10 +75,12 @@ type TeamProfileResponse struct {
 	Description    string    `json:"description"`
 	AvatarURL      string    `json:"avatar_url"`
 	OrganizationID uint      `json:"organization_id"`
+	ParentID       *uint     `json:"parent_id"`
 	LeaderID       *uint     `json:"leader_id"`
 	CreatedAt      time.Time `json:"created_at"`
 
 	Organization *OrganizationResponse `json:"organization,omitempty"`
+	Parent       *TeamResponse         `json:"parent,omitempty"`
 	Leader       *UserSimpleResponse   `json:"leader,omitempty"`
 	Members      []UserSimpleResponse  `json:"members,omitempty"`
 }
@@ -74,3 +96,46 @@ type OrganizationProfileResponse struct {
 	Teams []TeamResponse       `json:"teams,omitempty"`
 	Users []UserSimpleResponse `json:"users,omitempty"`
 }
+
+type OrgRoleResponse struct {
+	ID             uint         `json:"id"`
+	OrganizationID uint         `json:"organization_id"`
+	Name           string       `json:"name"`
+	BaseRole       Role         `json:"base_role"`
+	IsSystem       bool         `json:"is_system"`
+	Permissions    []Permission `json:"permissions"`
+	MembersCount   int64        `json:"members_count"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type APITokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []APIScope `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Events      []string  `json:"events"`
+	CreatedByID uint      `json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type MembershipResponse struct {
+	OrganizationID uint                  `json:"organization_id"`
+	Organization   *OrganizationResponse `json:"organization,omitempty"`
+	Role           Role                  `json:"role"`
+	OrgRoleID      *uint                 `json:"org_role_id"`
+	TeamID         *uint                 `json:"team_id"`
+	IsActive       bool                  `json:"is_active"`
+	JoinedAt       time.Time             `json:"joined_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,6 +17,14 @@ const (
 	RoleSuperAdmin Role = 4
 )
 
+type TeamRole string
+
+const (
+	TeamRoleMember TeamRole = "member"
+	TeamRoleDeputy TeamRole = "deputy"
+	TeamRoleLeader TeamRole = "leader"
+)
+
 type User struct {
 	ID        uint   `gorm:"primaryKey" json:"id"`
 	Email     string `gorm:"uniqueIndex;not null" json:"email"`
@@ -33,6 +42,34 @@ type User struct {
 
 	Role Role `gorm:"default:0" json:"role"`
 
+	OrgRoleID *uint    `json:"org_role_id"`
+	OrgRole   *OrgRole `gorm:"foreignKey:OrgRoleID" json:"org_role,omitempty"`
+
+	Version uint `gorm:"not null;default:0" json:"-"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TOTPSecret is set on enrollment and only active once TOTPEnabledAt is.
+	TOTPSecret    string     `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"totp_enabled_at"`
+	// TOTPLastStep is the last accepted time step, so a code works only once.
+	TOTPLastStep int64 `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Membership struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	UserID         uint          `gorm:"not null;uniqueIndex:idx_membership_user_org" json:"user_id"`
+	User           *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	OrganizationID uint          `gorm:"not null;uniqueIndex:idx_membership_user_org;index" json:"organization_id"`
+	Organization   *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+
+	Role      Role  `gorm:"default:0" json:"role"`
+	OrgRoleID *uint `json:"org_role_id"`
+	TeamID    *uint `json:"team_id"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -43,6 +80,8 @@ type Organization struct {
 	Description string `json:"description"`
 	AvatarURL   string `json:"avatar_url"`
 	OwnerID     uint   `json:"owner_id"`
+	// RequireTwoFactor makes admins and the owner enroll in 2FA.
+	RequireTwoFactor bool `gorm:"not null;default:false" json:"require_two_factor"`
 
 	Teams     []Team     `gorm:"constraint:OnDelete:CASCADE;" json:"teams,omitempty"`
 	News      []News     `gorm:"constraint:OnDelete:CASCADE;" json:"news,omitempty"`
@@ -52,6 +91,24 @@ type Organization struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type OrgRole struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	OrganizationID uint   `gorm:"not null;index:idx_org_role_name,unique" json:"organization_id"`
+	Name           string `gorm:"not null;index:idx_org_role_name,unique" json:"name"`
+	BaseRole       Role   `gorm:"not null" json:"base_role"`
+	IsSystem       bool   `gorm:"default:false" json:"is_system"`
+
+	Permissions []RolePermission `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RolePermission struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	OrgRoleID  uint       `gorm:"not null;index" json:"org_role_id"`
+	Permission Permission `gorm:"not null" json:"permission"`
+}
+
 type Team struct {
 	ID             uint          `gorm:"primaryKey" json:"id"`
 	Name           string        `gorm:"not null;index:idx_org_team_name,unique" json:"name"`
@@ -60,10 +117,23 @@ type Team struct {
 	OrganizationID uint          `gorm:"not null;index:idx_org_team_name,unique" json:"organization_id"`
 	Organization   *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 
+	ParentID *uint `gorm:"index" json:"parent_id"`
+
 	LeaderID *uint `json:"leader_id"`
 	Leader   *User `json:"leader" gorm:"foreignKey:LeaderID"`
 
-	Users []User `gorm:"foreignKey:TeamID" json:"users,omitempty"`
+	Members []TeamMember `gorm:"constraint:OnDelete:CASCADE;" json:"members,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TeamMember struct {
+	ID     uint     `gorm:"primaryKey" json:"id"`
+	TeamID uint     `gorm:"not null;uniqueIndex:idx_team_member" json:"team_id"`
+	Team   *Team    `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	UserID uint     `gorm:"not null;uniqueIndex:idx_team_member;index" json:"user_id"`
+	User   *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role   TeamRole `gorm:"type:varchar(16);not null;default:member" json:"role"`
 
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -76,12 +146,88 @@ type Invite struct {
 	ExpiresAt      time.Time      `gorm:"not null" json:"expires_at"`
 	MaxUses        int            `gorm:"default:1" json:"max_uses"`
 	Uses           int            `gorm:"default:0" json:"uses"`
+	Email          string         `json:"email,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	CreatedBy User `gorm:"foreignKey:CreatedByID" json:"created_by"`
 }
 
+type Session struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	UserAgent        string     `json:"user_agent"`
+	IP               string     `json:"ip"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ExternalIdentity links a user to their subject at an OpenID Connect provider.
+type ExternalIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	Provider    string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_external_identity" json:"provider"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_external_identity" json:"-"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type UserTokenPurpose string
+
+const (
+	TokenPasswordReset  UserTokenPurpose = "password_reset"
+	TokenEmailVerify    UserTokenPurpose = "email_verify"
+	TokenLoginChallenge UserTokenPurpose = "login_challenge"
+)
+
+// UserToken is a single-use token sent by email. Only its hash is stored.
+type UserToken struct {
+	ID        uint             `gorm:"primaryKey"`
+	UserID    uint             `gorm:"not null;index"`
+	Purpose   UserTokenPurpose `gorm:"type:varchar(32);not null"`
+	TokenHash string           `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time        `gorm:"not null"`
+	UsedAt    *time.Time
+	// Attempts counts wrong codes entered against a login challenge.
+	Attempts  int `gorm:"not null;default:0"`
+	CreatedAt time.Time
+}
+
+// APIToken is a personal token for scripts. It acts as its owner, limited
+// to its scopes. Only the hash is stored; Prefix helps users tell tokens apart.
+type APIToken struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	UserID     uint            `gorm:"not null;index" json:"-"`
+	Name       string          `gorm:"not null" json:"name"`
+	Prefix     string          `gorm:"type:varchar(16);not null" json:"prefix"`
+	TokenHash  string          `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes     []APITokenScope `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	ExpiresAt  *time.Time      `json:"expires_at"`
+	LastUsedAt *time.Time      `json:"last_used_at"`
+	LastUsedIP string          `json:"last_used_ip"`
+	RevokedAt  *time.Time      `json:"-"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type APITokenScope struct {
+	ID         uint     `gorm:"primaryKey"`
+	APITokenID uint     `gorm:"not null;index"`
+	Scope      APIScope `gorm:"not null"`
+}
+
+// RecoveryCode is a one-time backup code for 2FA. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
 type Tag struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
 	Name string `gorm:"uniqueIndex;not null" json:"name"`
@@ -145,6 +291,8 @@ type Task struct {
 	Status      TaskStatus   `gorm:"default:'TODO'" json:"status"`
 	Priority    TaskPriority `gorm:"default:'MEDIUM'" json:"priority"`
 	DueDate     *time.Time   `json:"due_date"`
+	// EstimateMinutes is the planned effort; logged time lives in TaskTimeLog.
+	EstimateMinutes *int `json:"estimate_minutes"`
 
 	OrganizationID uint `json:"organization_id"`
 	TeamID         uint `json:"team_id"`
@@ -155,6 +303,331 @@ type Task struct {
 	AssigneeID *uint `json:"assignee_id"`
 	Assignee   *User `gorm:"foreignKey:AssigneeID" json:"assignee"`
 
+	SprintID *uint `gorm:"index" json:"sprint_id"`
+
+	// ParentID makes the task a subtask; parent and child share a team.
+	ParentID *uint `gorm:"index" json:"parent_id"`
+
+	// RecurrenceID and OccurrenceAt identify a task generated from a
+	// recurring template; the pair is unique so an occurrence is created once.
+	RecurrenceID *uint      `gorm:"uniqueIndex:idx_task_occurrence" json:"recurrence_id"`
+	OccurrenceAt *time.Time `gorm:"uniqueIndex:idx_task_occurrence" json:"occurrence_at"`
+
+	// Subtasks and Progress are only filled when the subtree is requested.
+	Subtasks []Task `gorm:"-" json:"subtasks,omitempty"`
+	Progress *int   `gorm:"-" json:"progress,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SprintStatus string
+
+const (
+	SprintPlanned SprintStatus = "planned"
+	SprintActive  SprintStatus = "active"
+	SprintClosed  SprintStatus = "closed"
+)
+
+// Sprint groups a team's tasks over a date range. A team has at most one
+// active sprint at a time.
+type Sprint struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizationID uint         `gorm:"not null;index" json:"organization_id"`
+	TeamID         uint         `gorm:"not null;index" json:"team_id"`
+	Name           string       `gorm:"not null" json:"name"`
+	Goal           string       `json:"goal"`
+	StartDate      time.Time    `gorm:"not null" json:"start_date"`
+	EndDate        time.Time    `gorm:"not null" json:"end_date"`
+	Status         SprintStatus `gorm:"type:varchar(16);not null;default:'planned'" json:"status"`
+
+	StartedAt *time.Time `json:"started_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// TaskRecurrence is a template the scheduler turns into a new Task at every
+// occurrence of Rule, starting at StartsAt.
+type TaskRecurrence struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizationID uint         `gorm:"not null;index" json:"organization_id"`
+	TeamID         uint         `gorm:"not null;index" json:"team_id"`
+	CreatorID      uint         `gorm:"not null" json:"creator_id"`
+	Title          string       `gorm:"not null" json:"title"`
+	Description    string       `json:"description"`
+	Priority       TaskPriority `gorm:"default:'MEDIUM'" json:"priority"`
+	AssigneeID     *uint        `json:"assignee_id"`
+
+	// Rule is an RRULE subset, e.g. "FREQ=WEEKLY;BYDAY=MO".
+	Rule     string     `gorm:"not null" json:"rule"`
+	StartsAt time.Time  `gorm:"not null" json:"starts_at"`
+	Until    *time.Time `json:"until"`
+	// DueInDays sets the due date of each instance relative to its occurrence.
+	DueInDays int  `gorm:"not null;default:0" json:"due_in_days"`
+	Active    bool `gorm:"not null;default:true" json:"active"`
+
+	NextRunAt *time.Time `gorm:"index" json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaskTimeLog is time a user spent on a task. A log with StartedAt set and
+// StoppedAt empty is a running timer; its Minutes are filled when it stops.
+type TaskTimeLog struct {
+	ID      uint      `gorm:"primaryKey" json:"id"`
+	TaskID  uint      `gorm:"not null;index" json:"task_id"`
+	UserID  uint      `gorm:"not null;index" json:"user_id"`
+	User    *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Minutes int       `gorm:"not null;default:0" json:"minutes"`
+	Date    time.Time `gorm:"not null;index" json:"date"`
+	Note    string    `json:"note"`
+
+	StartedAt *time.Time `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AttachmentsDir holds task attachment files. It is outside the public
+// uploads directory, so files are only served by the download endpoint.
+const AttachmentsDir = "storage/attachments"
+
+type TaskAttachment struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	TaskID       uint   `gorm:"not null;index" json:"task_id"`
+	FilePath     string `gorm:"not null" json:"-"`
+	OriginalName string `json:"original_name"`
+	MimeType     string `json:"mime_type"`
+	Size         int64  `json:"size"`
+
+	UploaderID uint  `gorm:"not null" json:"uploader_id"`
+	Uploader   *User `gorm:"foreignKey:UploaderID" json:"uploader,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TaskChecklistItem struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	TaskID   uint   `gorm:"not null;index" json:"task_id"`
+	Title    string `gorm:"not null" json:"title"`
+	Done     bool   `gorm:"not null;default:false" json:"done"`
+	Position int    `gorm:"not null" json:"position"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskDependency records that TaskID cannot be finished before BlockedByID.
+type TaskDependency struct {
+	ID          uint  `gorm:"primaryKey" json:"id"`
+	TaskID      uint  `gorm:"not null;uniqueIndex:idx_task_dependency" json:"task_id"`
+	BlockedByID uint  `gorm:"not null;uniqueIndex:idx_task_dependency;index" json:"blocked_by_id"`
+	BlockedBy   *Task `gorm:"foreignKey:BlockedByID" json:"blocked_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TeamWorkflowStatus struct {
+	ID       uint       `gorm:"primaryKey" json:"id"`
+	TeamID   uint       `gorm:"not null;uniqueIndex:idx_team_workflow_status" json:"team_id"`
+	Key      TaskStatus `gorm:"type:varchar(32);not null;uniqueIndex:idx_team_workflow_status" json:"key"`
+	Name     string     `gorm:"not null" json:"name"`
+	Position int        `gorm:"not null" json:"position"`
+}
+
+type TeamWorkflowTransition struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	TeamID     uint       `gorm:"not null;index" json:"team_id"`
+	FromStatus TaskStatus `gorm:"type:varchar(32);not null" json:"from"`
+	ToStatus   TaskStatus `gorm:"type:varchar(32);not null" json:"to"`
+	// Actors is a comma-separated list of WorkflowActor values.
+	Actors string `gorm:"not null" json:"actors"`
+}
+
+type TaskComment struct {
+	ID       uint  `gorm:"primaryKey" json:"id"`
+	TaskID   uint  `gorm:"not null;index" json:"task_id"`
+	AuthorID uint  `gorm:"not null" json:"author_id"`
+	Author   *User `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	// Body is Markdown; rendering is left to the client.
+	Body string `gorm:"type:text;not null" json:"body"`
+
+	EditedAt  *time.Time `json:"edited_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TaskActivityAction string
+
+const (
+	ActivityCreated         TaskActivityAction = "created"
+	ActivityTitleChanged    TaskActivityAction = "title_changed"
+	ActivityStatusChanged   TaskActivityAction = "status_changed"
+	ActivityPriorityChanged TaskActivityAction = "priority_changed"
+	ActivityAssigneeChanged TaskActivityAction = "assignee_changed"
+	ActivityDueDateChanged  TaskActivityAction = "due_date_changed"
+	ActivityCommented       TaskActivityAction = "commented"
+	ActivityParentChanged   TaskActivityAction = "parent_changed"
+	ActivityBlockerAdded    TaskActivityAction = "blocker_added"
+	ActivityBlockerRemoved  TaskActivityAction = "blocker_removed"
+	ActivityEstimateChanged TaskActivityAction = "estimate_changed"
+	ActivitySprintChanged   TaskActivityAction = "sprint_changed"
+)
+
+type TaskActivity struct {
+	ID      uint               `gorm:"primaryKey" json:"id"`
+	TaskID  uint               `gorm:"not null;index" json:"task_id"`
+	ActorID uint               `gorm:"not null" json:"actor_id"`
+	Actor   *User              `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	Action  TaskActivityAction `gorm:"type:varchar(32);not null" json:"action"`
+	From    string             `json:"from"`
+	To      string             `json:"to"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationType string
+
+const (
+	NotifyTaskAssigned NotificationType = "task_assigned"
+	NotifyTeamAdded    NotificationType = "team_added"
+	NotifyTeamLeader   NotificationType = "team_leader"
+	NotifyNewsPosted   NotificationType = "news_posted"
+	// NotifyOverdueDigest is the daily email; it has no in-app notification.
+	NotifyOverdueDigest NotificationType = "overdue_digest"
+)
+
+var AllNotificationTypes = []NotificationType{
+	NotifyTaskAssigned, NotifyTeamAdded, NotifyTeamLeader, NotifyNewsPosted, NotifyOverdueDigest,
+}
+
+type Notification struct {
+	ID             uint             `gorm:"primaryKey" json:"id"`
+	UserID         uint             `gorm:"not null;index:idx_notification_user" json:"user_id"`
+	OrganizationID *uint            `gorm:"index" json:"organization_id"`
+	Type           NotificationType `gorm:"type:varchar(32);not null" json:"type"`
+	Title          string           `gorm:"not null" json:"title"`
+	Body           string           `json:"body"`
+	// EntityType and EntityID point at the task, team or news the notification is about.
+	EntityType string `json:"entity_type"`
+	EntityID   uint   `json:"entity_id"`
+
+	ActorID *uint `json:"actor_id"`
+	Actor   *User `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+
+	ReadAt    *time.Time `gorm:"index:idx_notification_user" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference turns one notification type off (or back on) for a
+// user. Types without a row are delivered.
+type NotificationPreference struct {
+	ID      uint             `gorm:"primaryKey" json:"-"`
+	UserID  uint             `gorm:"not null;uniqueIndex:idx_notification_pref" json:"-"`
+	Type    NotificationType `gorm:"type:varchar(32);not null;uniqueIndex:idx_notification_pref" json:"type"`
+	Enabled bool             `gorm:"not null" json:"enabled"`
+}
+
+// OverdueDigest records that a user got the overdue-task email for a day, so
+// restarts never send it twice.
+type OverdueDigest struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_overdue_digest_day"`
+	Day       string `gorm:"type:varchar(10);not null;uniqueIndex:idx_overdue_digest_day"`
+	CreatedAt time.Time
+}
+
+type AuditAction string
+
+const (
+	AuditMemberRoleChanged AuditAction = "member.role_changed"
+	AuditMemberKicked      AuditAction = "member.kicked"
+	AuditTeamDeleted       AuditAction = "team.deleted"
+	AuditTeamLeaderChanged AuditAction = "team.leader_changed"
+	AuditInviteCreated     AuditAction = "invite.created"
+	AuditInviteDeleted     AuditAction = "invite.deleted"
+	AuditDocumentDeleted   AuditAction = "document.deleted"
+	AuditNewsDeleted       AuditAction = "news.deleted"
+	AuditWebhookCreated    AuditAction = "webhook.created"
+	AuditWebhookUpdated    AuditAction = "webhook.updated"
+	AuditWebhookDeleted    AuditAction = "webhook.deleted"
+	AuditRoleCreated       AuditAction = "role.created"
+	AuditRoleUpdated       AuditAction = "role.updated"
+	AuditRoleDeleted       AuditAction = "role.deleted"
+	AuditOrgTwoFactor      AuditAction = "org.two_factor_changed"
+	AuditOrgTransferred    AuditAction = "org.ownership_transferred"
+	AuditOrgDeleted        AuditAction = "org.deleted"
+)
+
+// AuditEvent records an administrative action in an organization. Events are
+// never updated; only the retention job deletes them. Before and After hold
+// JSON with the fields the action changed.
+type AuditEvent struct {
+	ID             uint  `gorm:"primaryKey" json:"id"`
+	OrganizationID uint  `gorm:"not null;index:idx_audit_org_time" json:"organization_id"`
+	ActorID        *uint `gorm:"index" json:"actor_id"`
+	// ActorEmail keeps the actor readable after the account is deleted.
+	ActorEmail string      `json:"actor_email"`
+	APITokenID *uint       `json:"api_token_id"`
+	Action     AuditAction `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetType string      `gorm:"type:varchar(32);not null" json:"target_type"`
+	TargetID   uint        `json:"target_id"`
+	Before     string      `json:"before"`
+	After      string      `json:"after"`
+	IP         string      `json:"ip"`
+	UserAgent  string      `json:"user_agent"`
+	CreatedAt  time.Time   `gorm:"index:idx_audit_org_time" json:"created_at"`
+}
+
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("audit events are append-only")
+}
+
+// Webhook posts the organization's events of the subscribed types to URL,
+// signed with Secret.
+type Webhook struct {
+	ID             uint                  `gorm:"primaryKey" json:"id"`
+	OrganizationID uint                  `gorm:"not null;index" json:"organization_id"`
+	URL            string                `gorm:"not null" json:"url"`
+	Secret         string                `gorm:"not null" json:"-"`
+	Description    string                `json:"description"`
+	Active         bool                  `gorm:"not null" json:"active"`
+	CreatedByID    uint                  `json:"created_by_id"`
+	Events         []WebhookSubscription `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+type WebhookSubscription struct {
+	ID        uint   `gorm:"primaryKey"`
+	WebhookID uint   `gorm:"not null;index"`
+	EventType string `gorm:"type:varchar(64);not null"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one queued event for a webhook and the outcome of its
+// last attempt. Pending deliveries are sent once NextAttemptAt has passed.
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primaryKey" json:"id"`
+	WebhookID      uint                  `gorm:"not null;index" json:"webhook_id"`
+	EventID        string                `gorm:"type:varchar(36);not null;index" json:"event_id"`
+	EventType      string                `gorm:"type:varchar(64);not null" json:"event_type"`
+	Payload        string                `gorm:"not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(16);not null;index:idx_delivery_due" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time            `gorm:"index:idx_delivery_due" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `json:"response_body"`
+	Error          string                `json:"error"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
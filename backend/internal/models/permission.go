@@ -0,0 +1,97 @@
+package models
+
+type Permission string
+
+const (
+	PermOrgUpdate         Permission = "org.update"
+	PermMemberKick        Permission = "member.kick"
+	PermMemberManageRoles Permission = "member.manage_roles"
+	PermUserEditAny       Permission = "user.edit_any"
+	PermRoleManage        Permission = "role.manage"
+	PermAuditView         Permission = "audit.view"
+	PermWebhookManage     Permission = "webhook.manage"
+
+	PermTeamCreate        Permission = "team.create"
+	PermTeamUpdate        Permission = "team.update"
+	PermTeamDelete        Permission = "team.delete"
+	PermTeamManageMembers Permission = "team.manage_members"
+	PermTeamAssignLeader  Permission = "team.assign_leader"
+
+	PermInviteCreate Permission = "invite.create"
+	PermInviteManage Permission = "invite.manage"
+
+	PermNewsPublishGlobal  Permission = "news.publish_global"
+	PermNewsPublishAnyTeam Permission = "news.publish_any_team"
+	PermNewsModerate       Permission = "news.moderate"
+	PermNewsViewAll        Permission = "news.view_all"
+
+	PermDocumentUploadAnyTeam Permission = "document.upload_any_team"
+	PermDocumentModerate      Permission = "document.moderate"
+	PermDocumentViewAll       Permission = "document.view_all"
+
+	PermTaskCreateOwnTeam Permission = "task.create_own_team"
+	PermTaskDeleteOwnTeam Permission = "task.delete_own_team"
+	PermTaskCreate        Permission = "task.create"
+	PermTaskDelete        Permission = "task.delete"
+	PermTaskViewAll       Permission = "task.view_all"
+)
+
+var AllPermissions = []Permission{
+	PermOrgUpdate, PermMemberKick, PermMemberManageRoles, PermUserEditAny, PermRoleManage, PermAuditView, PermWebhookManage,
+	PermTeamCreate, PermTeamUpdate, PermTeamDelete, PermTeamManageMembers, PermTeamAssignLeader,
+	PermInviteCreate, PermInviteManage,
+	PermNewsPublishGlobal, PermNewsPublishAnyTeam, PermNewsModerate, PermNewsViewAll,
+	PermDocumentUploadAnyTeam, PermDocumentModerate, PermDocumentViewAll,
+	PermTaskCreateOwnTeam, PermTaskDeleteOwnTeam, PermTaskCreate, PermTaskDelete, PermTaskViewAll,
+}
+
+var employeePermissions = []Permission{
+	PermTaskCreateOwnTeam, PermTaskDeleteOwnTeam,
+}
+
+var managerPermissions = append(append([]Permission{}, employeePermissions...),
+	PermTaskCreate, PermTaskDelete, PermTaskViewAll,
+)
+
+var adminPermissions = append(append([]Permission{}, managerPermissions...),
+	PermOrgUpdate, PermMemberKick, PermUserEditAny, PermAuditView, PermWebhookManage,
+	PermTeamCreate, PermTeamUpdate, PermTeamDelete, PermTeamManageMembers, PermTeamAssignLeader,
+	PermInviteCreate, PermInviteManage,
+	PermNewsPublishGlobal, PermNewsPublishAnyTeam, PermNewsModerate, PermNewsViewAll,
+	PermDocumentUploadAnyTeam, PermDocumentModerate, PermDocumentViewAll,
+)
+
+// DefaultRolePermissions is used to seed the system roles of a new organization
+// and as a fallback for organizations that have no role rows yet.
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleUser:       {},
+	RoleEmployee:   employeePermissions,
+	RoleManager:    managerPermissions,
+	RoleAdmin:      adminPermissions,
+	RoleSuperAdmin: AllPermissions,
+}
+
+func IsValidPermission(p Permission) bool {
+	for _, known := range AllPermissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleUser:
+		return "User"
+	case RoleEmployee:
+		return "Employee"
+	case RoleManager:
+		return "Manager"
+	case RoleAdmin:
+		return "Admin"
+	case RoleSuperAdmin:
+		return "SuperAdmin"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceFrequency string
+
+const (
+	FrequencyDaily   RecurrenceFrequency = "DAILY"
+	FrequencyWeekly  RecurrenceFrequency = "WEEKLY"
+	FrequencyMonthly RecurrenceFrequency = "MONTHLY"
+)
+
+var weekdayOrder = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RecurrenceRule is a small subset of RFC 5545 RRULE:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY (weekly) and BYMONTHDAY (monthly).
+// Occurrences are counted from the rule's start time and keep its time of day.
+type RecurrenceRule struct {
+	Frequency  RecurrenceFrequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+}
+
+func ParseRecurrenceRule(value string) (RecurrenceRule, error) {
+	rule := RecurrenceRule{Interval: 1}
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	if value == "" {
+		return rule, fmt.Errorf("Rule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("Invalid rule part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			rule.Frequency = RecurrenceFrequency(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 365 {
+				return rule, fmt.Errorf("INTERVAL must be between 1 and 365")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("Unknown weekday %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 31 {
+				return rule, fmt.Errorf("BYMONTHDAY must be between 1 and 31")
+			}
+			rule.ByMonthDay = n
+		default:
+			return rule, fmt.Errorf("Unsupported rule part %s", key)
+		}
+	}
+
+	switch rule.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return rule, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+	}
+	if len(rule.ByDay) > 0 && rule.Frequency != FrequencyWeekly {
+		return rule, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.ByMonthDay > 0 && rule.Frequency != FrequencyMonthly {
+		return rule, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var codes []string
+		for _, code := range weekdayOrder {
+			if containsWeekday(r.ByDay, weekdayCodes[code]) {
+				codes = append(codes, code)
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.ByMonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given time.
+func (r RecurrenceRule) Next(start, after time.Time) time.Time {
+	start = start.Truncate(time.Second)
+	if after.Before(start) {
+		after = start.Add(-time.Nanosecond)
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		startWeek := mondayOf(start)
+		day := at(after)
+		if !day.After(after) {
+			day = day.AddDate(0, 0, 1)
+		}
+		for i := 0; i < 7*(interval+1); i++ {
+			weeks := int(mondayOf(day).Sub(startWeek).Hours()+12) / (24 * 7)
+			if weeks%interval == 0 && !day.Before(start) && containsWeekday(days, day.Weekday()) {
+				return day
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+	case FrequencyMonthly:
+		monthDay := r.ByMonthDay
+		if monthDay == 0 {
+			monthDay = start.Day()
+		}
+		year, month := after.Year(), after.Month()
+		for i := 0; i < 12*interval+2; i++ {
+			months := (year-start.Year())*12 + int(month) - int(start.Month())
+			if months >= 0 && months%interval == 0 {
+				day := at(time.Date(year, month, clampMonthDay(year, month, monthDay), 0, 0, 0, 0, start.Location()))
+				if day.After(after) && !day.Before(start) {
+					return day
+				}
+			}
+			month++
+			if month > time.December {
+				month = time.January
+				year++
+			}
+		}
+	default:
+		days := int(at(after).Sub(at(start)).Hours()+12) / 24
+		if days < 0 {
+			days = 0
+		}
+		k := days / interval * interval
+		for {
+			day := at(start).AddDate(0, 0, k)
+			if day.After(after) {
+				return day
+			}
+			k += interval
+		}
+	}
+	return time.Time{}
+}
+
+func mondayOf(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func clampMonthDay(year int, month time.Month, day int) int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		return last
+	}
+	return day
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestRecurrenceRuleNextMonthlyEndOfMonth(t *testing.T) {
+	jan31 := date(2026, time.January, 31, 9)
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+	}{
+		{"before start returns start", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, date(2026, time.January, 1, 0), jan31},
+		{"february clamps to 28th", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, jan31, date(2026, time.February, 28, 9)},
+		{"leap february clamps to 29th", "FREQ=MONTHLY;BYMONTHDAY=31", date(2028, time.January, 31, 9), date(2028, time.January, 31, 9), date(2028, time.February, 29, 9)},
+		{"back to 31st after february", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, date(2026, time.February, 28, 9), date(2026, time.March, 31, 9)},
+		{"30-day month clamps to 30th", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, date(2026, time.March, 31, 9), date(2026, time.April, 30, 9)},
+		{"later the same day", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, date(2026, time.April, 30, 8), date(2026, time.April, 30, 9)},
+		{"year rollover", "FREQ=MONTHLY;BYMONTHDAY=31", jan31, date(2026, time.December, 31, 9), date(2027, time.January, 31, 9)},
+		{"day defaults to the start day", "FREQ=MONTHLY", jan31, jan31, date(2026, time.February, 28, 9)},
+		{"interval skips months", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31", jan31, jan31, date(2026, time.March, 31, 9)},
+		{"interval lands on a short month", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=31", jan31, jan31, date(2026, time.April, 30, 9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q): %v", tt.rule, err)
+			}
+			if got := rule.Next(tt.start, tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%v, %v) = %v, want %v", tt.start, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+// Walking a year of occurrences must not drift: a clamped February 28th must
+// not turn the following months into the 28th.
+func TestRecurrenceRuleNextMonthlyWalk(t *testing.T) {
+	rule := RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, ByMonthDay: 31}
+	start := date(2026, time.January, 31, 9)
+	want := []int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+	at := start.Add(-time.Second)
+	for i, day := range want {
+		at = rule.Next(start, at)
+		if at.Day() != day || at.Month() != time.Month(i+1) || at.Hour() != 9 {
+			t.Fatalf("occurrence %d = %v, want 2026-%02d-%02d 09:00", i, at, i+1, day)
+		}
+	}
+}
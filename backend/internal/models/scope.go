@@ -0,0 +1,39 @@
+package models
+
+// APIScope limits what a personal API token can reach.
+type APIScope string
+
+const (
+	ScopeOrgRead        APIScope = "org:read"
+	ScopeNewsRead       APIScope = "news:read"
+	ScopeNewsWrite      APIScope = "news:write"
+	ScopeTasksRead      APIScope = "tasks:read"
+	ScopeTasksWrite     APIScope = "tasks:write"
+	ScopeDocumentsRead  APIScope = "documents:read"
+	ScopeDocumentsWrite APIScope = "documents:write"
+)
+
+var AllAPIScopes = []APIScope{
+	ScopeOrgRead,
+	ScopeNewsRead, ScopeNewsWrite,
+	ScopeTasksRead, ScopeTasksWrite,
+	ScopeDocumentsRead, ScopeDocumentsWrite,
+}
+
+func IsValidAPIScope(s APIScope) bool {
+	for _, known := range AllAPIScopes {
+		if known == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *APIToken) HasScope(s APIScope) bool {
+	for _, scope := range t.Scopes {
+		if scope.Scope == s {
+			return true
+		}
+	}
+	return false
+}
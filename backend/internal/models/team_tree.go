@@ -0,0 +1,65 @@
+package models
+
+// TeamTree is the parent/child structure of the teams of one organization.
+type TeamTree struct {
+	parents  map[uint]*uint
+	children map[uint][]uint
+}
+
+func NewTeamTree(teams []Team) *TeamTree {
+	tree := &TeamTree{
+		parents:  make(map[uint]*uint, len(teams)),
+		children: make(map[uint][]uint),
+	}
+	for _, team := range teams {
+		tree.parents[team.ID] = team.ParentID
+		if team.ParentID != nil {
+			tree.children[*team.ParentID] = append(tree.children[*team.ParentID], team.ID)
+		}
+	}
+	return tree
+}
+
+// Ancestors returns the parent chain of the team, nearest first.
+func (t *TeamTree) Ancestors(teamID uint) []uint {
+	var ids []uint
+	seen := map[uint]bool{teamID: true}
+	for parent := t.parents[teamID]; parent != nil && !seen[*parent]; parent = t.parents[*parent] {
+		seen[*parent] = true
+		ids = append(ids, *parent)
+	}
+	return ids
+}
+
+// Descendants returns every team below the given one.
+func (t *TeamTree) Descendants(teamID uint) []uint {
+	var ids []uint
+	seen := map[uint]bool{teamID: true}
+	queue := []uint{teamID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range t.children[current] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return ids
+}
+
+// IsDescendant reports whether teamID lies somewhere below ancestorID.
+func (t *TeamTree) IsDescendant(teamID, ancestorID uint) bool {
+	for _, id := range t.Ancestors(teamID) {
+		if id == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TeamTree) Children(teamID uint) []uint {
+	return t.children[teamID]
+}
@@ -0,0 +1,108 @@
+package models
+
+import "strings"
+
+// WorkflowActor names who may perform a status transition.
+type WorkflowActor string
+
+const (
+	ActorAnyone   WorkflowActor = "anyone"
+	ActorCreator  WorkflowActor = "creator"
+	ActorAssignee WorkflowActor = "assignee"
+	// ActorLeader is the team leader or a deputy, or anyone allowed to create tasks in any team.
+	ActorLeader WorkflowActor = "leader"
+)
+
+func IsValidWorkflowActor(a WorkflowActor) bool {
+	switch a {
+	case ActorAnyone, ActorCreator, ActorAssignee, ActorLeader:
+		return true
+	}
+	return false
+}
+
+type WorkflowStatus struct {
+	Key  TaskStatus `json:"key"`
+	Name string     `json:"name"`
+}
+
+type WorkflowTransition struct {
+	From   TaskStatus      `json:"from"`
+	To     TaskStatus      `json:"to"`
+	Actors []WorkflowActor `json:"actors"`
+}
+
+// Workflow lists the statuses of a team's tasks in board order and the
+// transitions allowed between them. Every workflow has StatusDone, which
+// marks a task as finished.
+type Workflow struct {
+	Statuses    []WorkflowStatus     `json:"statuses"`
+	Transitions []WorkflowTransition `json:"transitions"`
+	IsDefault   bool                 `json:"is_default"`
+}
+
+func DefaultWorkflow() Workflow {
+	anyone := []WorkflowActor{ActorAnyone}
+	approvers := []WorkflowActor{ActorCreator, ActorLeader}
+	return Workflow{
+		Statuses: []WorkflowStatus{
+			{Key: StatusTodo, Name: "To do"},
+			{Key: StatusInProgress, Name: "In progress"},
+			{Key: StatusReview, Name: "Review"},
+			{Key: StatusDone, Name: "Done"},
+		},
+		Transitions: []WorkflowTransition{
+			{From: StatusTodo, To: StatusInProgress, Actors: anyone},
+			{From: StatusInProgress, To: StatusTodo, Actors: anyone},
+			{From: StatusInProgress, To: StatusReview, Actors: anyone},
+			{From: StatusReview, To: StatusInProgress, Actors: anyone},
+			{From: StatusReview, To: StatusDone, Actors: approvers},
+			{From: StatusDone, To: StatusInProgress, Actors: approvers},
+		},
+		IsDefault: true,
+	}
+}
+
+func (w *Workflow) HasStatus(status TaskStatus) bool {
+	for _, s := range w.Statuses {
+		if s.Key == status {
+			return true
+		}
+	}
+	return false
+}
+
+// InitialStatus is the status new tasks start in.
+func (w *Workflow) InitialStatus() TaskStatus {
+	if len(w.Statuses) == 0 {
+		return StatusTodo
+	}
+	return w.Statuses[0].Key
+}
+
+func (w *Workflow) Transition(from, to TaskStatus) (WorkflowTransition, bool) {
+	for _, t := range w.Transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return WorkflowTransition{}, false
+}
+
+func JoinWorkflowActors(actors []WorkflowActor) string {
+	parts := make([]string, len(actors))
+	for i, a := range actors {
+		parts[i] = string(a)
+	}
+	return strings.Join(parts, ",")
+}
+
+func SplitWorkflowActors(s string) []WorkflowActor {
+	var actors []WorkflowActor
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			actors = append(actors, WorkflowActor(part))
+		}
+	}
+	return actors
+}
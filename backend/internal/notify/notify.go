@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"corp-portal/internal/models"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Event describes something that happened and who should hear about it.
+type Event struct {
+	Type           models.NotificationType
+	OrganizationID *uint
+	ActorID        uint
+	Recipients     []uint
+	Title          string
+	Body           string
+	EntityType     string
+	EntityID       uint
+}
+
+// Publish stores a notification for every recipient that has not muted the
+// event type. The actor is never notified about their own action. Failures
+// are logged rather than returned so they never break the calling request.
+func Publish(db *gorm.DB, event Event) {
+	seen := map[uint]bool{event.ActorID: true}
+	var recipients []uint
+	for _, id := range event.Recipients {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	var muted []uint
+	if err := db.Model(&models.NotificationPreference{}).
+		Where("type = ? AND enabled = ? AND user_id IN ?", event.Type, false, recipients).
+		Pluck("user_id", &muted).Error; err != nil {
+		log.Println("Notifications: failed to load preferences:", err)
+		return
+	}
+	for _, id := range muted {
+		seen[id] = false
+	}
+
+	var actorID *uint
+	if event.ActorID != 0 {
+		actorID = &event.ActorID
+	}
+
+	notifications := make([]models.Notification, 0, len(recipients))
+	for _, userID := range recipients {
+		if !seen[userID] {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:         userID,
+			OrganizationID: event.OrganizationID,
+			Type:           event.Type,
+			Title:          event.Title,
+			Body:           event.Body,
+			EntityType:     event.EntityType,
+			EntityID:       event.EntityID,
+			ActorID:        actorID,
+		})
+	}
+	if len(notifications) == 0 {
+		return
+	}
+	if err := db.Create(&notifications).Error; err != nil {
+		log.Println("Notifications: failed to store:", err)
+	}
+}
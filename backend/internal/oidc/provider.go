@@ -0,0 +1,160 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Provider is an OpenID Connect identity provider whose ID tokens we accept.
+// JWKSURL and TokenURL are discovered from the issuer when left empty.
+type Provider struct {
+	Name        string
+	DisplayName string
+	Issuer      string
+	// Aliases are other iss values the provider uses, e.g. Google's scheme-less one.
+	Aliases      []string
+	ClientID     string
+	ClientSecret string
+	JWKSURL      string
+	TokenURL     string
+	// RedirectURI is used for code exchanges that do not pass one.
+	RedirectURI string
+	// TrustEmail accepts the email claim without email_verified, for
+	// directories such as a company Keycloak that never send it.
+	TrustEmail bool
+
+	mu           sync.Mutex
+	discovered   bool
+	keys         map[string]interface{}
+	keysLoadedAt time.Time
+}
+
+var providers = map[string]*Provider{}
+
+// Setup registers Google when GOOGLE_CLIENT_ID is set and every provider
+// listed in OIDC_PROVIDERS, configured by OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET, _DISPLAY_NAME, _JWKS_URL, _TOKEN_URL and _TRUST_EMAIL.
+func Setup() {
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		providers["google"] = &Provider{
+			Name:         "google",
+			DisplayName:  "Google",
+			Issuer:       "https://accounts.google.com",
+			Aliases:      []string{"accounts.google.com"},
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			JWKSURL:      "https://www.googleapis.com/oauth2/v3/certs",
+			TokenURL:     "https://oauth2.googleapis.com/token",
+			RedirectURI:  "postmessage",
+		}
+	}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		env := func(key string) string {
+			return os.Getenv("OIDC_" + strings.ToUpper(name) + "_" + key)
+		}
+		provider := &Provider{
+			Name:         name,
+			DisplayName:  env("DISPLAY_NAME"),
+			Issuer:       strings.TrimRight(env("ISSUER"), "/"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			JWKSURL:      env("JWKS_URL"),
+			TokenURL:     env("TOKEN_URL"),
+			RedirectURI:  env("REDIRECT_URI"),
+			TrustEmail:   env("TRUST_EMAIL") == "true",
+		}
+		if provider.DisplayName == "" {
+			provider.DisplayName = name
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Fatalf("OIDC provider %q needs OIDC_%s_ISSUER and OIDC_%s_CLIENT_ID", name, strings.ToUpper(name), strings.ToUpper(name))
+		}
+		providers[name] = provider
+	}
+}
+
+func Get(name string) (*Provider, bool) {
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+func List() []*Provider {
+	list := make([]*Provider, 0, len(providers))
+	for _, provider := range providers {
+		list = append(list, provider)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (p *Provider) validIssuer(iss string) bool {
+	iss = strings.TrimRight(iss, "/")
+	if iss == p.Issuer {
+		return true
+	}
+	for _, alias := range p.Aliases {
+		if iss == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// discover fills in the endpoints from the issuer's discovery document.
+// The caller holds p.mu.
+func (p *Provider) discover(ctx context.Context) error {
+	if p.discovered || (p.JWKSURL != "" && p.TokenURL != "") {
+		return nil
+	}
+
+	var doc struct {
+		Issuer        string `json:"issuer"`
+		JWKSURI       string `json:"jwks_uri"`
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return fmt.Errorf("discovery: %w", err)
+	}
+	if !p.validIssuer(doc.Issuer) {
+		return fmt.Errorf("discovery: issuer %q does not match %q", doc.Issuer, p.Issuer)
+	}
+	if p.JWKSURL == "" {
+		p.JWKSURL = doc.JWKSURI
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = doc.TokenEndpoint
+	}
+	p.discovered = true
+	return nil
+}
+
+func getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,125 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the ID token claims used to find or create the local account.
+type Claims struct {
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Picture           string   `json:"picture"`
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts both true and "true"; some providers send the string.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// Verify checks the ID token's signature against the provider's JWKS and its
+// issuer, audience, expiry and, when given, nonce.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.validIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID {
+		return nil, errors.New("token was issued to another client")
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return claims, nil
+}
+
+// Exchange trades an authorization code for the provider's ID token.
+func (p *Provider) Exchange(ctx context.Context, code, redirectURI, codeVerifier string) (string, error) {
+	p.mu.Lock()
+	err := p.discover(ctx)
+	tokenURL := p.TokenURL
+	p.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if tokenURL == "" {
+		return "", errors.New("provider has no token endpoint")
+	}
+	if redirectURI == "" {
+		redirectURI = p.RedirectURI
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+		"client_id":    {p.ClientID},
+	}
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		if body.Error != "" {
+			return "", fmt.Errorf("token endpoint: %s %s", body.Error, body.ErrorDescription)
+		}
+		return "", fmt.Errorf("token endpoint: %s without id_token", resp.Status)
+	}
+	return body.IDToken, nil
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import "time"
+
+// Lockout locks a key after Threshold failures within Window. Each further
+// failure doubles the lock, starting at Base and capped at Max.
+type Lockout struct {
+	Name      string
+	Threshold int
+	Window    time.Duration
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Locked returns how long the key stays locked, or zero.
+func (l Lockout) Locked(key string) time.Duration {
+	count, resetIn := Default.Get(l.Name + ":lock:" + key)
+	if count == 0 {
+		return 0
+	}
+	return resetIn
+}
+
+// Fail records a failure and returns the lock it triggered, if any.
+func (l Lockout) Fail(key string) time.Duration {
+	failures, _ := Default.Incr(l.Name+":fail:"+key, l.Window)
+	if failures < l.Threshold {
+		return 0
+	}
+
+	lock := l.Max
+	if shift := failures - l.Threshold; shift < 16 && l.Base<<shift < l.Max {
+		lock = l.Base << shift
+	}
+	Default.Incr(l.Name+":lock:"+key, lock)
+	return lock
+}
+
+// Reset clears the failures after a successful attempt.
+func (l Lockout) Reset(key string) {
+	Default.Reset(l.Name + ":fail:" + key)
+	Default.Reset(l.Name + ":lock:" + key)
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rule allows Limit requests per Window for each key. Name keeps the
+// buckets of different rules apart.
+type Rule struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// Allow counts a request for key and, when over the limit, returns how long
+// to wait.
+func (r Rule) Allow(key string) (bool, time.Duration) {
+	count, resetIn := Default.Incr(r.Name+":"+key, r.Window)
+	return count <= r.Limit, resetIn
+}
+
+// KeyFunc picks the bucket for a request. An empty key skips the limit.
+type KeyFunc func(c *gin.Context) string
+
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByUser buckets by the authenticated user, so it must run after AuthMiddleware.
+func ByUser(c *gin.Context) string {
+	if userID := c.GetUint("userID"); userID != 0 {
+		return fmt.Sprint(userID)
+	}
+	return ""
+}
+
+func Middleware(rule Rule, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+		if ok, retryAfter := rule.Allow(k); !ok {
+			LogEvent(c, "rate_limited", "rule=%s key=%s", rule.Name, k)
+			TooManyRequests(c, retryAfter, "Too many requests, try again later")
+			return
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests aborts with 429 and a Retry-After header in whole seconds.
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message, "retry_after": seconds})
+}
+
+// LogEvent writes a security event to the log with the client IP and route.
+func LogEvent(c *gin.Context, event, format string, args ...interface{}) {
+	log.Printf("security: %s ip=%s path=%s %s", event, c.ClientIP(), c.Request.URL.Path, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Store keeps fixed-window counters. Incr maps onto Redis INCR plus EXPIRE,
+// so a shared store can replace the in-memory one when running several
+// instances.
+type Store interface {
+	// Incr adds a hit to key, starting a window of the given length if none
+	// is open, and returns the hits in the window and the time until it ends.
+	Incr(key string, window time.Duration) (count int, resetIn time.Duration)
+	// Get returns the same as Incr without adding a hit.
+	Get(key string) (count int, resetIn time.Duration)
+	Reset(key string)
+}
+
+// Default is the store used by the middleware and lockouts.
+var Default Store = NewMemoryStore()
+
+type memoryEntry struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore is a Store local to the process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Incr(key string, window time.Duration) (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.resetAt) {
+		entry = &memoryEntry{resetAt: now.Add(window)}
+		s.entries[key] = entry
+	}
+	entry.count++
+	return entry.count, entry.resetAt.Sub(now)
+}
+
+func (s *MemoryStore) Get(key string) (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.resetAt) {
+		return 0, 0
+	}
+	return entry.count, entry.resetAt.Sub(now)
+}
+
+func (s *MemoryStore) Reset(key string) {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+}
+
+// sweep drops expired windows once a minute. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for key, entry := range s.entries {
+		if !now.Before(entry.resetAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
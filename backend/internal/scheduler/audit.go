@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// StartAuditRetention deletes audit events older than AUDIT_RETENTION_DAYS
+// (365 by default). Zero keeps them forever.
+func StartAuditRetention(every time.Duration) {
+	days := 365
+	if value, err := strconv.Atoi(os.Getenv("AUDIT_RETENTION_DAYS")); err == nil && value >= 0 {
+		days = value
+	}
+	if days == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for now := time.Now(); ; now = <-ticker.C {
+			PruneAuditEvents(now.AddDate(0, 0, -days))
+		}
+	}()
+}
+
+func PruneAuditEvents(before time.Time) {
+	result := database.DB.Where("created_at < ?", before).Delete(&models.AuditEvent{})
+	if result.Error != nil {
+		log.Println("Audit retention: failed to delete events:", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Audit retention: deleted %d events before %s", result.RowsAffected, before.Format("2006-01-02"))
+	}
+}
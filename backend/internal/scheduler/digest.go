@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/mailer"
+	"corp-portal/internal/models"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type digestTask struct {
+	Title   string
+	DueDate string
+}
+
+// StartOverdueDigests emails every assignee the list of their overdue tasks
+// once a day, on the first tick after DIGEST_HOUR (server time, 8 by default).
+// Sent days are stored in overdue_digests.
+func StartOverdueDigests(every time.Duration) {
+	hour := 8
+	if value, err := strconv.Atoi(os.Getenv("DIGEST_HOUR")); err == nil && value >= 0 && value < 24 {
+		hour = value
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for now := time.Now(); ; now = <-ticker.C {
+			if now.Hour() >= hour {
+				RunOverdueDigests(now)
+			}
+		}
+	}()
+}
+
+func RunOverdueDigests(now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := today.Format("2006-01-02")
+
+	var rows []struct {
+		models.Task
+		Email    string
+		FullName string
+	}
+	if err := database.DB.Table("tasks").
+		Select("tasks.*, users.email, users.full_name").
+		Joins("JOIN users ON users.id = tasks.assignee_id").
+		Joins("JOIN memberships ON memberships.user_id = tasks.assignee_id AND memberships.organization_id = tasks.organization_id").
+		Where("tasks.due_date < ? AND tasks.status != ?", today, models.StatusDone).
+		Where("tasks.assignee_id NOT IN (?)", database.DB.Model(&models.NotificationPreference{}).
+			Select("user_id").Where("type = ? AND enabled = ?", models.NotifyOverdueDigest, false)).
+		Where("tasks.assignee_id NOT IN (?)", database.DB.Model(&models.OverdueDigest{}).
+			Select("user_id").Where("day = ?", day)).
+		Order("tasks.assignee_id, tasks.due_date").
+		Scan(&rows).Error; err != nil {
+		log.Println("Overdue digests: failed to load tasks:", err)
+		return
+	}
+
+	for start := 0; start < len(rows); {
+		end := start
+		var tasks []digestTask
+		for ; end < len(rows) && *rows[end].AssigneeID == *rows[start].AssigneeID; end++ {
+			tasks = append(tasks, digestTask{Title: rows[end].Title, DueDate: rows[end].DueDate.Format("2006-01-02")})
+		}
+		sendOverdueDigest(*rows[start].AssigneeID, rows[start].Email, rows[start].FullName, day, tasks)
+		start = end
+	}
+}
+
+func sendOverdueDigest(userID uint, email, name, day string, tasks []digestTask) {
+	// The row is claimed first so parallel runs send once; it is released
+	// again if the mail fails so the next tick retries.
+	digest := models.OverdueDigest{UserID: userID, Day: day}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&digest)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	err := mailer.SendTemplate(email, mailer.DefaultLocale(), "overdue_digest", map[string]interface{}{
+		"Name":  name,
+		"Tasks": tasks,
+		"Link":  mailer.Link("/tasks"),
+	})
+	if err != nil {
+		log.Printf("Overdue digests: failed to email user %d: %v", userID, err)
+		database.DB.Delete(&digest)
+	}
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/models"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartRecurringTasks creates due task instances now and then on every tick.
+// Progress is stored in task_recurrences.next_run_at and every instance is
+// keyed by (recurrence_id, occurrence_at), so restarts never duplicate tasks.
+func StartRecurringTasks(every time.Duration) {
+	go func() {
+		RunRecurringTasks(time.Now())
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			RunRecurringTasks(now)
+		}
+	}()
+}
+
+func RunRecurringTasks(now time.Time) {
+	now = now.UTC()
+
+	var due []models.TaskRecurrence
+	if err := database.DB.Where("active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Println("Recurring tasks: failed to load templates:", err)
+		return
+	}
+
+	for i := range due {
+		if err := runRecurrence(database.DB, &due[i], now); err != nil {
+			log.Printf("Recurring tasks: template %d failed: %v", due[i].ID, err)
+		}
+	}
+}
+
+// NextRunAt returns the first occurrence of the template after the given
+// time, or nil once the rule has run past Until.
+func NextRunAt(recurrence *models.TaskRecurrence, rule models.RecurrenceRule, after time.Time) *time.Time {
+	next := rule.Next(recurrence.StartsAt, after).UTC()
+	if next.IsZero() || (recurrence.Until != nil && next.After(*recurrence.Until)) {
+		return nil
+	}
+	return &next
+}
+
+func runRecurrence(db *gorm.DB, recurrence *models.TaskRecurrence, now time.Time) error {
+	rule, err := models.ParseRecurrenceRule(recurrence.Rule)
+	if err != nil {
+		db.Model(recurrence).Update("active", false)
+		return err
+	}
+
+	// Only the latest missed occurrence is created after downtime.
+	occurrence := recurrence.NextRunAt.UTC()
+	for {
+		next := NextRunAt(recurrence, rule, occurrence)
+		if next == nil || next.After(now) {
+			break
+		}
+		occurrence = *next
+	}
+
+	var created *models.Task
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&models.Task{}).Where("recurrence_id = ? AND occurrence_at = ?", recurrence.ID, occurrence).Count(&count)
+		if count == 0 {
+			task, err := createInstance(tx, recurrence, occurrence)
+			if err != nil {
+				return err
+			}
+			created = task
+		}
+
+		return tx.Model(recurrence).Updates(map[string]interface{}{
+			"next_run_at": NextRunAt(recurrence, rule, occurrence),
+			"last_run_at": occurrence,
+		}).Error
+	})
+	if err == nil && created != nil {
+		events.Publish(events.TaskEvent(events.TaskCreated, created, *created))
+	}
+	return err
+}
+
+func createInstance(tx *gorm.DB, recurrence *models.TaskRecurrence, occurrence time.Time) (*models.Task, error) {
+	workflow := models.DefaultWorkflow()
+	status := workflow.InitialStatus()
+	var first models.TeamWorkflowStatus
+	err := tx.Where("team_id = ?", recurrence.TeamID).Order("position asc").First(&first).Error
+	if err == nil {
+		status = first.Key
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	assigneeID := recurrence.AssigneeID
+	if assigneeID != nil {
+		var count int64
+		tx.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ?", *assigneeID, recurrence.OrganizationID).Count(&count)
+		if count == 0 {
+			assigneeID = nil
+		}
+	}
+
+	dueDate := occurrence.AddDate(0, 0, recurrence.DueInDays)
+	occurrenceAt := occurrence
+	task := models.Task{
+		Title:          recurrence.Title,
+		Description:    recurrence.Description,
+		Priority:       recurrence.Priority,
+		Status:         status,
+		DueDate:        &dueDate,
+		AssigneeID:     assigneeID,
+		TeamID:         recurrence.TeamID,
+		CreatorID:      recurrence.CreatorID,
+		OrganizationID: recurrence.OrganizationID,
+		RecurrenceID:   &recurrence.ID,
+		OccurrenceAt:   &occurrenceAt,
+	}
+	if err := tx.Create(&task).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.TaskActivity{
+		TaskID:  task.ID,
+		ActorID: recurrence.CreatorID,
+		Action:  models.ActivityCreated,
+		To:      task.Title,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
@@ -2,26 +2,34 @@ package utils
 
 import (
 	"corp-portal/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type Claims struct {
-	UserID uint        `json:"user_id"`
-	Role   models.Role `json:"role"`
+	UserID    uint `json:"user_id"`
+	SessionID uint `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user models.User) (string, error) {
+func GenerateToken(user models.User, sessionID uint) (string, error) {
 	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
 
 	claims := Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -35,7 +43,7 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -47,3 +55,18 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return nil, err
 }
+
+// GenerateRefreshToken returns an opaque random token and the hash that is stored in the database.
+func GenerateRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"bytes"
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	maxAttempts     = 8
+	batchSize       = 20
+	concurrency     = 4
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+	// claimDuration keeps a delivery from being picked up again while it is
+	// being sent.
+	claimDuration      = time.Minute
+	responseBodyLimit  = 2048
+	deliveryRetention  = 30 * 24 * time.Hour
+	deliveryPruneEvery = time.Hour
+	requestTimeout     = 10 * time.Second
+	signatureHeader    = "X-Portal-Signature"
+	timestampHeader    = "X-Portal-Timestamp"
+	eventHeader        = "X-Portal-Event"
+	deliveryHeader     = "X-Portal-Delivery"
+	webhookUserAgent   = "CorpPortal-Webhooks/1.0"
+)
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+var client = &http.Client{
+	Timeout: requestTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: checkAddress}).DialContext,
+	},
+	// Redirects are not followed; a 3xx counts as a failed attempt.
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// checkAddress runs after DNS resolution, so a public hostname that
+// resolves to an internal address is refused too.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	if allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Sign returns the signature header value for a payload: the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay doubles from 30 seconds after each failed attempt, up to 6 hours.
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// StartDeliveries sends due deliveries on every tick and as soon as new ones
+// are queued. Finished deliveries are kept for 30 days.
+func StartDeliveries(every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		var lastPrune time.Time
+		for {
+			now := time.Now()
+			RunDeliveries(now)
+			if now.Sub(lastPrune) >= deliveryPruneEvery {
+				pruneDeliveries(now.Add(-deliveryRetention))
+				lastPrune = now
+			}
+			select {
+			case <-ticker.C:
+			case <-wakeup:
+			}
+		}
+	}()
+}
+
+func RunDeliveries(now time.Time) {
+	var due []models.WebhookDelivery
+	if err := database.DB.
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at asc").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		log.Println("Webhooks: failed to load deliveries:", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i := range due {
+		delivery := &due[i]
+		// Claim the delivery so another tick or instance does not send it too.
+		claim := database.DB.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryPending, now).
+			Update("next_attempt_at", now.Add(claimDuration))
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			attempt(delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+func attempt(delivery *models.WebhookDelivery) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"last_attempt_at": now,
+		"response_status": 0,
+		"response_body":   "",
+		"error":           "",
+	}
+
+	var hook models.Webhook
+	if err := database.DB.First(&hook, delivery.WebhookID).Error; err != nil {
+		updates["status"] = models.DeliveryFailed
+		updates["next_attempt_at"] = nil
+		updates["error"] = "webhook no longer exists"
+		database.DB.Model(delivery).Updates(updates)
+		return
+	}
+
+	status, body, err := post(&hook, delivery)
+	updates["response_status"] = status
+	updates["response_body"] = body
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		updates["status"] = models.DeliverySucceeded
+		updates["next_attempt_at"] = nil
+	case delivery.Attempts+1 >= maxAttempts:
+		updates["status"] = models.DeliveryFailed
+		updates["next_attempt_at"] = nil
+	default:
+		updates["next_attempt_at"] = now.Add(retryDelay(delivery.Attempts + 1))
+	}
+	if err != nil {
+		updates["error"] = err.Error()
+	} else if status < 200 || status >= 300 {
+		updates["error"] = "unexpected status " + strconv.Itoa(status)
+	}
+
+	if err := database.DB.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("Webhooks: failed to record delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func post(hook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(eventHeader, delivery.EventType)
+	req.Header.Set(deliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(timestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	return resp.StatusCode, string(respBody), nil
+}
+
+func pruneDeliveries(before time.Time) {
+	if err := database.DB.
+		Where("status != ? AND created_at < ?", models.DeliveryPending, before).
+		Delete(&models.WebhookDelivery{}).Error; err != nil {
+		log.Println("Webhooks: failed to prune deliveries:", err)
+	}
+}
@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Webhook{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+}
+
+func setAllowPrivate(t *testing.T, allow bool) {
+	t.Helper()
+	previous := allowPrivate
+	allowPrivate = allow
+	t.Cleanup(func() { allowPrivate = previous })
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1","type":"task.created"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("whsec_test", 1700000000, body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	// The timestamp is signed too, so a captured request cannot be replayed later.
+	if Sign("whsec_test", 1700000001, body) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+	if Sign("whsec_other", 1700000000, body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:443", false},
+		{"172.16.0.1:443", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:443", false},
+		{"169.254.169.254:80", false},
+		{"0.0.0.0:80", false},
+		{"224.0.0.1:80", false},
+	}
+
+	setAllowPrivate(t, false)
+	for _, tt := range tests {
+		err := checkAddress("tcp", tt.address, nil)
+		if tt.public && err != nil {
+			t.Errorf("checkAddress(%s) = %v, want nil", tt.address, err)
+		}
+		if !tt.public && err != errPrivateAddress {
+			t.Errorf("checkAddress(%s) = %v, want %v", tt.address, err, errPrivateAddress)
+		}
+	}
+	if err := checkAddress("tcp", "no-port", nil); err == nil {
+		t.Error("checkAddress accepted an address without a port")
+	}
+
+	allowPrivate = true
+	for _, tt := range tests {
+		if err := checkAddress("tcp", tt.address, nil); err != nil {
+			t.Errorf("checkAddress(%s) with private addresses allowed = %v, want nil", tt.address, err)
+		}
+	}
+}
+
+// TestDeliveryRetry sends a delivery to a receiver that fails once and then
+// accepts it.
+func TestDeliveryRetry(t *testing.T) {
+	setupTestDB(t)
+	setAllowPrivate(t, true)
+
+	var status, requests atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(timestampHeader), 10, 64)
+		if r.Header.Get(signatureHeader) != Sign("whsec_test", timestamp, body) {
+			t.Errorf("bad signature %q", r.Header.Get(signatureHeader))
+		}
+		if r.Header.Get(eventHeader) != "task.created" {
+			t.Errorf("%s = %q, want task.created", eventHeader, r.Header.Get(eventHeader))
+		}
+		w.WriteHeader(int(status.Load()))
+		io.WriteString(w, "receiver says hi")
+	}))
+	defer server.Close()
+
+	hook := models.Webhook{OrganizationID: 1, URL: server.URL, Secret: "whsec_test", Active: true}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	delivery := models.WebhookDelivery{WebhookID: hook.ID, EventID: "event-1", EventType: "task.created", Payload: `{"id":"event-1"}`}
+	if err := Queue(&delivery); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+
+	load := func() models.WebhookDelivery {
+		t.Helper()
+		var d models.WebhookDelivery
+		if err := database.DB.First(&d, delivery.ID).Error; err != nil {
+			t.Fatalf("load delivery: %v", err)
+		}
+		return d
+	}
+
+	RunDeliveries(time.Now())
+	got := load()
+	if got.Status != models.DeliveryPending || got.Attempts != 1 || got.ResponseStatus != http.StatusInternalServerError ||
+		got.ResponseBody != "receiver says hi" || got.Error != "unexpected status 500" {
+		t.Fatalf("after a 500: status %s, attempts %d, response %d %q, error %q",
+			got.Status, got.Attempts, got.ResponseStatus, got.ResponseBody, got.Error)
+	}
+	if got.NextAttemptAt == nil || time.Until(*got.NextAttemptAt) < 25*time.Second {
+		t.Fatalf("retry scheduled at %v, want about 30 seconds from now", got.NextAttemptAt)
+	}
+
+	// Not due yet.
+	RunDeliveries(time.Now())
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("receiver got %d requests before the retry was due, want 1", n)
+	}
+
+	status.Store(http.StatusNoContent)
+	RunDeliveries(time.Now().Add(time.Minute))
+	got = load()
+	if got.Status != models.DeliverySucceeded || got.Attempts != 2 || got.ResponseStatus != http.StatusNoContent ||
+		got.Error != "" || got.NextAttemptAt != nil {
+		t.Fatalf("after the retry: status %s, attempts %d, response %d, error %q, next %v",
+			got.Status, got.Attempts, got.ResponseStatus, got.Error, got.NextAttemptAt)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("receiver got %d requests, want 2", n)
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	setupTestDB(t)
+	setAllowPrivate(t, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	hook := models.Webhook{OrganizationID: 1, URL: server.URL, Secret: "whsec_test", Active: true}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	delivery := models.WebhookDelivery{WebhookID: hook.ID, EventID: "event-1", EventType: "task.created", Payload: `{}`}
+	if err := Queue(&delivery); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+	database.DB.Model(&delivery).Update("attempts", maxAttempts-1)
+
+	RunDeliveries(time.Now())
+	var got models.WebhookDelivery
+	database.DB.First(&got, delivery.ID)
+	if got.Status != models.DeliveryFailed || got.Attempts != maxAttempts || got.NextAttemptAt != nil {
+		t.Fatalf("after the last attempt: status %s, attempts %d, next %v", got.Status, got.Attempts, got.NextAttemptAt)
+	}
+}
@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"corp-portal/internal/database"
+	"corp-portal/internal/events"
+	"corp-portal/internal/models"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payload is the JSON body posted to webhooks. ID stays the same when a
+// delivery is retried or replayed, so receivers can drop duplicates.
+type Payload struct {
+	ID             string      `json:"id"`
+	Type           string      `json:"type"`
+	OrganizationID uint        `json:"organization_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Data           interface{} `json:"data"`
+}
+
+// allowPrivate lets webhooks reach loopback and private addresses, which
+// is needed for local development (WEBHOOK_ALLOW_PRIVATE=true).
+var allowPrivate bool
+
+var wakeup = make(chan struct{}, 1)
+
+// Setup forwards every published event to the organization's webhooks.
+func Setup() {
+	allowPrivate = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+	events.AddSink(enqueue)
+}
+
+func enqueue(event events.Event) {
+	var hooks []models.Webhook
+	if err := database.DB.
+		Joins("JOIN webhook_subscriptions ON webhook_subscriptions.webhook_id = webhooks.id").
+		Where("webhooks.organization_id = ? AND webhooks.active = ? AND webhook_subscriptions.event_type = ?", event.OrganizationID, true, event.Type).
+		Find(&hooks).Error; err != nil {
+		log.Printf("Webhooks: failed to load subscriptions for %s: %v", event.Type, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload := Payload{
+		ID:             uuid.New().String(),
+		Type:           event.Type,
+		OrganizationID: event.OrganizationID,
+		CreatedAt:      time.Now().UTC(),
+		Data:           event.Data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Webhooks: failed to encode %s: %v", event.Type, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		delivery := models.WebhookDelivery{
+			WebhookID: hook.ID,
+			EventID:   payload.ID,
+			EventType: payload.Type,
+			Payload:   string(body),
+		}
+		if err := Queue(&delivery); err != nil {
+			log.Printf("Webhooks: failed to queue %s for webhook %d: %v", event.Type, hook.ID, err)
+		}
+	}
+}
+
+// Queue stores a pending delivery and wakes the sender.
+func Queue(delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	if err := database.DB.Create(delivery).Error; err != nil {
+		return err
+	}
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+	return nil
+}
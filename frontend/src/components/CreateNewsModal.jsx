@@ -8,6 +8,7 @@ import CloudUploadOutlinedIcon from '@mui/icons-material/CloudUploadOutlined';
 import SendOutlinedIcon from '@mui/icons-material/SendOutlined';
 import SaveOutlinedIcon from '@mui/icons-material/SaveOutlined';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 
 export default function CreateNewsModal({ open, onClose, onSuccess, initialData = null }) {
   const { user } = useAuth();
@@ -33,16 +34,13 @@ export default function CreateNewsModal({ open, onClose, onSuccess, initialData
 
   useEffect(() => {
     if (open) {
-      const token = localStorage.getItem('token');
-      const headers = { 'Authorization': `Bearer ${token}` };
-
-      fetch('http://localhost:8080/api/tags', { headers })
+      apiFetch('/tags')
         .then(res => res.json())
         .then(data => setAvailableTags(data || []))
         .catch(err => console.error("Ошибка загрузки тегов", err));
 
       if (isAdminPlus) {
-        fetch('http://localhost:8080/api/teamsIn', { headers })
+        apiFetch('/teamsIn')
           .then(res => res.json())
           .then(data => setAvailableTeams(data || []))
           .catch(err => console.error("Ошибка загрузки команд", err));
@@ -92,14 +90,10 @@ export default function CreateNewsModal({ open, onClose, onSuccess, initialData
         data.append('target_team_id', formData.selectedTeam.id);
       }
 
-      const token = localStorage.getItem('token');
-      const url = isEdit 
-        ? `http://localhost:8080/api/news/${initialData.id}` 
-        : 'http://localhost:8080/api/news';
+      const url = isEdit ? `/news/${initialData.id}` : '/news';
       
-      const response = await fetch(url, {
+      const response = await apiFetch(url, {
         method: isEdit ? 'PUT' : 'POST',
-        headers: { 'Authorization': `Bearer ${token}` },
         body: data 
       });
 
@@ -7,6 +7,7 @@ import {
 import DeleteOutlineOutlinedIcon from '@mui/icons-material/DeleteOutlineOutlined';
 import CloseOutlinedIcon from '@mui/icons-material/CloseOutlined';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 
 export default function TaskModal({ open, onClose, task, onSuccess, teamId }) {
   const { user } = useAuth();
@@ -28,9 +29,7 @@ export default function TaskModal({ open, onClose, task, onSuccess, teamId }) {
 
   useEffect(() => {
     if (open && targetTeamId) {
-      fetch(`http://localhost:8080/api/teams/${targetTeamId}`, {
-        headers: { 'Authorization': `Bearer ${localStorage.getItem('token')}` }
-      })
+      apiFetch(`/teams/${targetTeamId}`)
       .then(res => res.json())
       .then(data => setMembers(Array.isArray(data.members) ? data.members : []))
       .catch(() => setMembers([]));
@@ -58,7 +57,7 @@ export default function TaskModal({ open, onClose, task, onSuccess, teamId }) {
   const handleSave = async () => {
     if (!formData.title.trim()) return alert("Введите заголовок");
     
-    const url = task ? `http://localhost:8080/api/tasks/${task.id}` : 'http://localhost:8080/api/tasks';
+    const url = task ? `/tasks/${task.id}` : '/tasks';
     const body = { 
         title: formData.title,
         description: formData.description,
@@ -68,9 +67,9 @@ export default function TaskModal({ open, onClose, task, onSuccess, teamId }) {
         due_date: formData.due_date ? new Date(formData.due_date).toISOString() : null
     };
 
-    const res = await fetch(url, {
+    const res = await apiFetch(url, {
       method: task ? 'PUT' : 'POST',
-      headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${localStorage.getItem('token')}` },
+      headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(body)
     });
 
@@ -85,10 +84,7 @@ export default function TaskModal({ open, onClose, task, onSuccess, teamId }) {
 
   const handleDelete = async () => {
     if (!window.confirm("Удалить задачу безвозвратно?")) return;
-    await fetch(`http://localhost:8080/api/tasks/${task.id}`, {
-        method: 'DELETE',
-        headers: { 'Authorization': `Bearer ${localStorage.getItem('token')}` }
-    });
+    await apiFetch(`/tasks/${task.id}`, { method: 'DELETE' });
     onSuccess();
     onClose();
   };
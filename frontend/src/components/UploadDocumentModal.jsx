@@ -6,6 +6,7 @@ import {
 } from '@mui/material';
 import CloudUploadOutlinedIcon from '@mui/icons-material/CloudUploadOutlined';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 
 export default function UploadDocumentModal({ open, onClose, onSuccess }) {
   const { user } = useAuth();
@@ -30,16 +31,13 @@ export default function UploadDocumentModal({ open, onClose, onSuccess }) {
 
   useEffect(() => {
     if (open) {
-      const token = localStorage.getItem('token');
-      const headers = { 'Authorization': `Bearer ${token}` };
-
-      fetch('http://localhost:8080/api/tags', { headers })
+      apiFetch('/tags')
         .then(res => res.json())
         .then(data => setAvailableTags(data || []))
         .catch(err => console.error("Ошибка тегов:", err));
 
       if (isAdminPlus) {
-        fetch('http://localhost:8080/api/teamsIn', { headers })
+        apiFetch('/teamsIn')
           .then(res => res.json())
           .then(data => setAvailableTeams(data || []))
           .catch(err => console.error("Ошибка команд:", err));
@@ -69,9 +67,8 @@ export default function UploadDocumentModal({ open, onClose, onSuccess }) {
           data.append('target_team_id', formData.selectedTeam.id);
       }
 
-      const response = await fetch('http://localhost:8080/api/documents', {
+      const response = await apiFetch('/documents', {
         method: 'POST',
-        headers: { 'Authorization': `Bearer ${localStorage.getItem('token')}` },
         body: data
       });
 
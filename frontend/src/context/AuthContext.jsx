@@ -20,16 +20,20 @@ export const AuthProvider = ({ children }) => {
         setUser(data);
       } catch (error) {
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         setUser(null);
       }
     }
     setLoading(false);
   };
 
-const login = async (email, password) => {
+  // При включённой 2FA сервер вместо токена возвращает challenge_token
+  const login = async (email, password) => {
     try {
       const { data } = await api.post('/login', { email, password });
+      if (data.two_factor_required) return { challengeToken: data.challenge_token };
       localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
       setUser(data.user);
       await checkUser(); 
       toast.success('Добро пожаловать!');
@@ -40,10 +44,12 @@ const login = async (email, password) => {
     }
   };
 
-  const googleAuth = async (accessToken) => {
+  const googleAuth = async (code) => {
     try {
-      const { data } = await api.post('/auth/google', { token: accessToken });
+      const { data } = await api.post('/auth/google', { code });
+      if (data.two_factor_required) return { challengeToken: data.challenge_token };
       localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
       setUser(data.user);
 
       await checkUser(); 
@@ -57,6 +63,21 @@ const login = async (email, password) => {
     }
   };
 
+  const verifyTwoFactor = async (challengeToken, code) => {
+    try {
+      const { data } = await api.post('/login/2fa', { challenge_token: challengeToken, code });
+      localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
+      setUser(data.user);
+      await checkUser();
+      toast.success('Добро пожаловать!');
+      return true;
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Неверный код');
+      return false;
+    }
+  };
+
   const register = async (formData) => {
     try {
       await api.post('/register', formData);
@@ -68,8 +89,14 @@ const login = async (email, password) => {
     }
   };
 
-  const logout = () => {
+  const logout = async () => {
+    try {
+      await api.post('/logout');
+    } catch (error) {
+      console.error("Logout Error:", error);
+    }
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     setUser(null);
     toast.success('Вы вышли из системы');
   };
@@ -80,6 +107,7 @@ const login = async (email, password) => {
       login, 
       register, 
       googleAuth,
+      verifyTwoFactor,
       logout, 
       loading, 
       checkUser 
@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 import { 
   Box, Typography, Container, Grid, Paper, TextField, 
   InputAdornment, Button, Chip, Skeleton, Card, CardMedia, CardActionArea,
@@ -45,15 +46,10 @@ export default function Dashboard() {
 
   useEffect(() => {
     const fetchMetadata = async () => {
-        const token = localStorage.getItem('token');
-        const headers = { 'Authorization': `Bearer ${token}` };
         try {
-            const requests = [
-                fetch('http://localhost:8080/api/tags', { headers }),
-                fetch('http://localhost:8080/api/authors', { headers })
-            ];
+            const requests = [apiFetch('/tags'), apiFetch('/authors')];
             if (isAdminPlus) {
-                requests.push(fetch('http://localhost:8080/api/teamsIn', { headers }));
+                requests.push(apiFetch('/teamsIn'));
             }
 
             const responses = await Promise.all(requests);
@@ -76,9 +72,7 @@ export default function Dashboard() {
         if (selectedAuthors.length > 0) params.append('author_ids', selectedAuthors.map(a => a.id).join(','));
         if (selectedTeams.length > 0) params.append('team_ids', selectedTeams.map(t => t.id).join(','));
 
-        const response = await fetch(`http://localhost:8080/api/news?${params.toString()}`, {
-            headers: { 'Authorization': `Bearer ${localStorage.getItem('token')}` }
-        });
+        const response = await apiFetch(`/news?${params.toString()}`);
         if (response.ok) setNews(await response.json());
       } catch (error) { console.error(error); }
       finally { setLoading(false); }
@@ -96,11 +90,7 @@ export default function Dashboard() {
     const handleDeleteNews = async (id) => {
         if (!window.confirm("Вы уверены, что хотите удалить эту новость?")) return;
         try {
-            const token = localStorage.getItem('token');
-            const response = await fetch(`http://localhost:8080/api/news/${id}`, {
-                method: 'DELETE',
-                headers: { 'Authorization': `Bearer ${token}` }
-            });
+            const response = await apiFetch(`/news/${id}`, { method: 'DELETE' });
             if (response.ok) setRefreshTrigger(prev => prev + 1);
         } catch (error) { console.error(error); }
     };
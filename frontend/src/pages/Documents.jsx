@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 import { 
   Box, Typography, Container, Grid, Paper, TextField, 
   InputAdornment, Button, Chip, Skeleton, Card, CardActionArea, 
@@ -40,15 +41,10 @@ export default function Documents() {
 
   useEffect(() => {
     const fetchMetadata = async () => {
-        const token = localStorage.getItem('token');
-        const headers = { 'Authorization': `Bearer ${token}` };
         try {
-            const requests = [
-                fetch('http://localhost:8080/api/tags', { headers }),
-                fetch('http://localhost:8080/api/authors', { headers })
-            ];
+            const requests = [apiFetch('/tags'), apiFetch('/authors')];
             if (isAdminPlus) {
-                requests.push(fetch('http://localhost:8080/api/teamsIn', { headers }));
+                requests.push(apiFetch('/teamsIn'));
             }
             const responses = await Promise.all(requests);
             if (responses[0].ok) setAvailableTags(await responses[0].json());
@@ -64,16 +60,13 @@ export default function Documents() {
       if (!user?.organization_id) return;
       setLoading(true);
       try {
-        const token = localStorage.getItem('token');
         const params = new URLSearchParams();
         if (search) params.append('search', search);
         if (selectedTags.length > 0) params.append('tag_ids', selectedTags.map(t => t.id).join(','));
         if (selectedAuthors.length > 0) params.append('author_ids', selectedAuthors.map(a => a.id).join(','));
         if (selectedTeams.length > 0) params.append('team_ids', selectedTeams.map(t => t.id).join(','));
         
-        const response = await fetch(`http://localhost:8080/api/documents?${params.toString()}`, {
-            headers: { 'Authorization': `Bearer ${token}` }
-        });
+        const response = await apiFetch(`/documents?${params.toString()}`);
         if (response.ok) {
           const data = await response.json();
           setDocs(Array.isArray(data) ? data : []);
@@ -87,11 +80,7 @@ export default function Documents() {
 
   const handleDownload = async (docId, fallbackName) => {
     try {
-        const token = localStorage.getItem('token');
-        const response = await fetch(`http://localhost:8080/api/documents/download/${docId}`, {
-            method: 'GET',
-            headers: { 'Authorization': `Bearer ${token}` }
-        });
+        const response = await apiFetch(`/documents/download/${docId}`);
 
         if (!response.ok) throw new Error('Ошибка скачивания');
 
@@ -122,11 +111,7 @@ export default function Documents() {
   const handleDeleteDoc = async (id) => {
     if (!window.confirm("Удалить документ навсегда?")) return;
     try {
-        const token = localStorage.getItem('token');
-        const res = await fetch(`http://localhost:8080/api/documents/${id}`, {
-            method: 'DELETE',
-            headers: { 'Authorization': `Bearer ${token}` }
-        });
+        const res = await apiFetch(`/documents/${id}`, { method: 'DELETE' });
         if (res.ok) setRefreshTrigger(p => p + 1);
     } catch (e) { console.error(e); }
   };
@@ -1,6 +1,6 @@
 import { useState } from 'react';
 import { useAuth } from '../context/AuthContext';
-import { useNavigate, Link as RouterLink } from 'react-router-dom';
+import { useNavigate, useLocation, Link as RouterLink } from 'react-router-dom';
 import { useGoogleLogin } from '@react-oauth/google';
 import { 
   Container, Box, TextField, Button, Typography, Paper, 
@@ -15,8 +15,11 @@ import GoogleIcon from '../components/GoogleIcon';
 export default function Login() {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [code, setCode] = useState('');
+  const location = useLocation();
+  const [challengeToken, setChallengeToken] = useState(location.state?.challengeToken || null);
   const [loading, setLoading] = useState(false);
-  const { login, googleAuth } = useAuth();
+  const { login, googleAuth, verifyTwoFactor } = useAuth();
   const navigate = useNavigate();
   const theme = useTheme();
 
@@ -24,10 +27,12 @@ export default function Login() {
   const isDark = theme.palette.mode === 'dark';
 
   const handleGoogleLogin = useGoogleLogin({
-    onSuccess: async (tokenResponse) => {
+    flow: 'auth-code',
+    onSuccess: async ({ code }) => {
       setLoading(true);
-      const success = await googleAuth(tokenResponse.access_token);
-      if (success) navigate('/');
+      const result = await googleAuth(code);
+      if (result?.challengeToken) setChallengeToken(result.challengeToken);
+      else if (result) navigate('/');
       setLoading(false);
     },
     onError: () => setLoading(false),
@@ -36,7 +41,16 @@ export default function Login() {
   const handleSubmit = async (e) => {
     e.preventDefault();
     setLoading(true);
-    const success = await login(email, password);
+    const result = await login(email, password);
+    if (result?.challengeToken) setChallengeToken(result.challengeToken);
+    else if (result) navigate('/');
+    setLoading(false);
+  };
+
+  const handleTwoFactorSubmit = async (e) => {
+    e.preventDefault();
+    setLoading(true);
+    const success = await verifyTwoFactor(challengeToken, code);
     if (success) navigate('/');
     setLoading(false);
   };
@@ -93,6 +107,47 @@ export default function Login() {
             Добро пожаловать в Croco
           </Typography>
 
+          {challengeToken ? (
+          <Box component="form" onSubmit={handleTwoFactorSubmit} sx={{ width: '100%' }}>
+            <Typography variant="body2" sx={{ color: 'text.secondary', mb: 1 }}>
+              Введите код из приложения-аутентификатора или один из резервных кодов
+            </Typography>
+            <TextField
+              margin="normal" required fullWidth
+              label="Код подтверждения"
+              autoComplete="one-time-code"
+              autoFocus
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              InputProps={{ sx: { borderRadius: '10px' } }}
+            />
+            <Button
+              type="submit"
+              fullWidth
+              variant="contained"
+              disableElevation
+              disabled={loading}
+              sx={{
+                mt: 4, mb: 2, py: 1.5,
+                borderRadius: '10px',
+                fontWeight: 700,
+                textTransform: 'none',
+                fontSize: '0.95rem',
+                bgcolor: ACCENT_COLOR,
+                '&:hover': { bgcolor: alpha(ACCENT_COLOR, 0.8) }
+              }}
+            >
+              {loading ? 'Загрузка...' : 'Подтвердить'}
+            </Button>
+            <Button
+              fullWidth
+              onClick={() => { setChallengeToken(null); setCode(''); }}
+              sx={{ textTransform: 'none', color: 'text.secondary' }}
+            >
+              Назад
+            </Button>
+          </Box>
+          ) : (
           <Box component="form" onSubmit={handleSubmit} sx={{ width: '100%' }}>
             <TextField
               margin="normal" required fullWidth 
@@ -187,6 +242,7 @@ export default function Login() {
               </Link>
             </Grid>
           </Box>
+          )}
         </Paper>
       </Container>
     </Box>
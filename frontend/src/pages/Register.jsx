@@ -23,10 +23,12 @@ export default function Register() {
   const ACCENT_COLOR = theme.palette.primary.main;
 
   const handleGoogleLogin = useGoogleLogin({
-    onSuccess: async (tokenResponse) => {
+    flow: 'auth-code',
+    onSuccess: async ({ code }) => {
       setLoading(true);
-      const success = await googleAuth(tokenResponse.access_token);
-      if (success) navigate('/');
+      const result = await googleAuth(code);
+      if (result?.challengeToken) navigate('/login', { state: { challengeToken: result.challengeToken } });
+      else if (result) navigate('/');
       setLoading(false);
     },
     onError: () => setLoading(false),
@@ -9,6 +9,7 @@ import AddCircleOutlineIcon from '@mui/icons-material/AddCircleOutline';
 import CalendarTodayOutlinedIcon from '@mui/icons-material/CalendarTodayOutlined';
 import PersonOutlineIcon from '@mui/icons-material/PersonOutline';
 import { useAuth } from '../context/AuthContext';
+import { apiFetch } from '../utils/api';
 import TaskModal from '../components/TaskModal';
 
 const COLUMNS = {
@@ -49,10 +50,7 @@ export default function TaskBoard() {
 
     if (isAdminPlus) {
       const fetchTeams = async () => {
-        const token = localStorage.getItem('token');
-        const res = await fetch('http://localhost:8080/api/teamsIn', {
-          headers: { 'Authorization': `Bearer ${token}` }
-        });
+        const res = await apiFetch('/teamsIn');
         if (res.ok) {
           const data = await res.json();
           setAvailableTeams(data || []);
@@ -67,13 +65,10 @@ export default function TaskBoard() {
   const fetchTasks = async () => {
     if (!selectedTeamId) return;
     try {
-      const token = localStorage.getItem('token');
-      const headers = { 'Authorization': `Bearer ${token}` };
-
       // Загружаем задачи и детали команды параллельно
       const [tasksRes, teamRes] = await Promise.all([
-        fetch(`http://localhost:8080/api/tasks?team_id=${selectedTeamId}`, { headers }),
-        fetch(`http://localhost:8080/api/teams/${selectedTeamId}`, { headers })
+        apiFetch(`/tasks?team_id=${selectedTeamId}`),
+        apiFetch(`/teams/${selectedTeamId}`)
       ]);
 
       if (tasksRes.ok) {
@@ -108,18 +103,22 @@ export default function TaskBoard() {
     const taskIndex = tasks.findIndex(t => t.id.toString() === draggableId);
     if (tasks[taskIndex].status === destination.droppableId) return;
 
+    const previousStatus = tasks[taskIndex].status;
     const updatedTasks = [...tasks];
     updatedTasks[taskIndex].status = destination.droppableId;
     setTasks(updatedTasks);
 
-    await fetch(`http://localhost:8080/api/tasks/${draggableId}`, {
+    const res = await apiFetch(`/tasks/${draggableId}`, {
       method: 'PUT',
-      headers: { 
-        'Content-Type': 'application/json',
-        'Authorization': `Bearer ${localStorage.getItem('token')}` 
-      },
+      headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ status: destination.droppableId })
     });
+
+    if (!res.ok) {
+      const err = await res.json().catch(() => ({}));
+      setTasks(current => current.map(t => t.id.toString() === draggableId ? { ...t, status: previousStatus } : t));
+      alert(err.error || "Не удалось изменить статус");
+    }
   };
 
   if (!selectedTeamId && !isAdminPlus) {
@@ -21,16 +21,71 @@ api.interceptors.request.use(
   }
 );
 
+let refreshPromise = null;
+
+const refreshAccessToken = async () => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    throw new Error('No refresh token');
+  }
+  const { data } = await axios.post(`${api.defaults.baseURL}/token/refresh`, {
+    refresh_token: refreshToken,
+  });
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refresh_token', data.refresh_token);
+  return data.token;
+};
+
+// Requests waiting on a 401 share one refresh.
+const refreshSession = async () => {
+  try {
+    refreshPromise = refreshPromise || refreshAccessToken();
+    return await refreshPromise;
+  } catch (refreshError) {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    window.location.href = '/login';
+    toast.error('Сессия истекла. Пожалуйста, войдите снова.');
+    return null;
+  } finally {
+    refreshPromise = null;
+  }
+};
+
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
-    if (error.response?.status === 401) {
-      localStorage.removeItem('token');
-      window.location.href = '/login';
-      toast.error('Сессия истекла. Пожалуйста, войдите снова.');
+  async (error) => {
+    const originalRequest = error.config;
+    if (error.response?.status === 401 && originalRequest && !originalRequest._retry) {
+      originalRequest._retry = true;
+      const token = await refreshSession();
+      if (token) {
+        originalRequest.headers.Authorization = `Bearer ${token}`;
+        return api(originalRequest);
+      }
     }
     return Promise.reject(error);
   }
 );
 
+// apiFetch is fetch against the API with the same token handling as the
+// axios instance, for callers that need the raw Response (FormData uploads,
+// file downloads).
+export const apiFetch = async (path, options = {}) => {
+  const send = (token) => {
+    const headers = new Headers(options.headers);
+    if (token) {
+      headers.set('Authorization', `Bearer ${token}`);
+    }
+    return fetch(`${api.defaults.baseURL}${path}`, { ...options, headers });
+  };
+
+  const response = await send(localStorage.getItem('token'));
+  if (response.status !== 401) {
+    return response;
+  }
+  const token = await refreshSession();
+  return token ? send(token) : response;
+};
+
 export default api;
\ No newline at end of file